@@ -0,0 +1,150 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/lavinas/cadoc6334/internal/config"
+)
+
+// flags holds the command line values common to every subcommand
+type flags struct {
+	configPath  string
+	dbHost      string
+	dbPort      int
+	dbUser      string
+	dbPassword  string
+	dbName      string
+	dbSSLMode   string
+	inDir       string
+	outDir      string
+	period      string
+	document    string
+	reports     string
+	cnpjBase    string
+	reconFile   string
+	reconFormat string
+	rounding    string
+	overflow    string
+	layoutVer   string
+	maxErrors   int
+	profile     string
+	pixFrom     string
+	pixTo       string
+	incremental bool
+	dupKey      string
+	dupReport   string
+	dupFlag     bool
+}
+
+// flagGroup selects the flags registered for a command besides the common ones
+// a flag of a group the command does not register is rejected on parsing
+type flagGroup int
+
+const (
+	// dbFlags are the database connection settings
+	dbFlags flagGroup = 1 << iota
+	// reconFlags are the reconciliation report settings
+	reconFlags
+	// pixFlags are the PIX DIMP generation settings
+	pixFlags
+	// duplicateKeyFlags is the key of the duplicated PIX records
+	duplicateKeyFlags
+)
+
+// newFlags registers the common flags and the flags of the given groups on a flag set
+func newFlags(fs *flag.FlagSet, groups flagGroup) *flags {
+	f := &flags{}
+	fs.StringVar(&f.configPath, "config", "", "path to the YAML config file (env CADOC_CONFIG)")
+	if groups&dbFlags != 0 {
+		fs.StringVar(&f.dbHost, "db-host", "", "database host (env CADOC_DB_HOST)")
+		fs.IntVar(&f.dbPort, "db-port", 0, "database port (env CADOC_DB_PORT)")
+		fs.StringVar(&f.dbUser, "db-user", "", "database user (env CADOC_DB_USER)")
+		fs.StringVar(&f.dbPassword, "db-password", "", "database password (env CADOC_DB_PASSWORD)")
+		fs.StringVar(&f.dbName, "db-name", "", "database name (env CADOC_DB_NAME)")
+		fs.StringVar(&f.dbSSLMode, "db-sslmode", "", "database ssl mode (env CADOC_DB_SSLMODE)")
+	}
+	fs.StringVar(&f.inDir, "in", "", "input directory (env CADOC_IN_DIR)")
+	fs.StringVar(&f.outDir, "out", "", "output directory (env CADOC_OUT_DIR)")
+	fs.StringVar(&f.period, "period", "", "reference period as YYYYQn, e.g. 2025Q3 (env CADOC_PERIOD)")
+	fs.StringVar(&f.document, "document", "", "CADOC document of the report files (env CADOC_DOCUMENT); default 6334")
+	fs.StringVar(&f.reports, "reports", "", "comma separated report names, e.g. RANKING,DESCONTO (env CADOC_REPORTS)")
+	fs.StringVar(&f.cnpjBase, "cnpj-base", "", "CNPJ base (8 digits) of the filing institution (env CADOC_CNPJ_BASE)")
+	if groups&reconFlags != 0 {
+		fs.StringVar(&f.reconFile, "recon-file", "", "write the reconciliation report to this file (env CADOC_RECON_FILE)")
+		fs.StringVar(&f.reconFormat, "recon-format", "", "reconciliation report format: json, csv or html; default from the file extension (env CADOC_RECON_FORMAT)")
+	}
+	fs.StringVar(&f.rounding, "rounding", "", "rounding mode of amounts and rates: half_up or half_even (env CADOC_ROUNDING)")
+	fs.StringVar(&f.overflow, "overflow", "", "text wider than its field: reject (default) or truncate (env CADOC_OVERFLOW)")
+	fs.StringVar(&f.layoutVer, "layout-version", "", "layout version of the input files; default detected from the period and record length (env CADOC_LAYOUT_VERSION)")
+	fs.StringVar(&f.profile, "profile", "", "output profile of the generated files, defined under profiles in the config file (env CADOC_PROFILE)")
+	if groups&pixFlags != 0 {
+		fs.StringVar(&f.pixFrom, "from", "", "first PIX transaction date generated, as YYYY-MM-DD (env CADOC_PIX_FROM)")
+		fs.StringVar(&f.pixTo, "to", "", "last PIX transaction date generated, as YYYY-MM-DD (env CADOC_PIX_TO)")
+		fs.BoolVar(&f.incremental, "incremental", false, "only rewrite the PIX days not yet generated or changed since the last run (env CADOC_PIX_INCREMENTAL)")
+		fs.StringVar(&f.dupReport, "duplicate-report", "", "write the duplicated PIX records found to this CSV file (env CADOC_PIX_DUP_REPORT)")
+		fs.BoolVar(&f.dupFlag, "flag-duplicates", false, "set the duplicated column of the duplicated PIX records found (env CADOC_PIX_DUP_FLAG)")
+	}
+	if groups&duplicateKeyFlags != 0 {
+		fs.StringVar(&f.dupKey, "duplicate-key", "", "comma separated PIX fields identifying a transaction within a day; default DataTransacao,NSU (env CADOC_PIX_DUP_KEY)")
+	}
+	fs.IntVar(&f.maxErrors, "max-errors", 0, "line errors reported per input file; default 100 (env CADOC_MAX_ERRORS)")
+	return f
+}
+
+// apply overlays the flags explicitly set on the command line on the configuration
+func (f *flags) apply(fs *flag.FlagSet, cfg *config.Config) {
+	fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "db-host":
+			cfg.Database.Host = f.dbHost
+		case "db-port":
+			cfg.Database.Port = f.dbPort
+		case "db-user":
+			cfg.Database.User = f.dbUser
+		case "db-password":
+			cfg.Database.Password = f.dbPassword
+		case "db-name":
+			cfg.Database.Name = f.dbName
+		case "db-sslmode":
+			cfg.Database.SSLMode = f.dbSSLMode
+		case "in":
+			cfg.Paths.In = f.inDir
+		case "out":
+			cfg.Paths.Out = f.outDir
+		case "period":
+			cfg.Period = f.period
+		case "document":
+			cfg.Document = f.document
+		case "reports":
+			cfg.Reports = config.SplitList(f.reports)
+		case "cnpj-base":
+			cfg.Institution.CNPJBase = f.cnpjBase
+		case "recon-file":
+			cfg.Output.ReconciliationFile = f.reconFile
+		case "recon-format":
+			cfg.Output.ReconciliationFormat = f.reconFormat
+		case "rounding":
+			cfg.Rounding = f.rounding
+		case "overflow":
+			cfg.Overflow = f.overflow
+		case "layout-version":
+			cfg.LayoutVersion = f.layoutVer
+		case "profile":
+			cfg.Profile = f.profile
+		case "max-errors":
+			cfg.MaxErrors = f.maxErrors
+		case "from":
+			cfg.Pix.From = f.pixFrom
+		case "to":
+			cfg.Pix.To = f.pixTo
+		case "incremental":
+			cfg.Pix.Incremental = f.incremental
+		case "duplicate-key":
+			cfg.Pix.Duplicates.Key = config.SplitList(f.dupKey)
+		case "duplicate-report":
+			cfg.Pix.Duplicates.Report = f.dupReport
+		case "flag-duplicates":
+			cfg.Pix.Duplicates.Flag = f.dupFlag
+		}
+	})
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/lavinas/cadoc6334/internal/adapter"
+	"github.com/lavinas/cadoc6334/internal/config"
+	"github.com/lavinas/cadoc6334/internal/domain"
+	"github.com/lavinas/cadoc6334/internal/usecase"
+)
+
+const usage = `Usage: cadoc <command> [flags]
+
+Commands:
+  generate    generate the CADOC 6334 report files from the database
+  reconcile   reconcile the CADOC 6334 input files against the database
+  reconcile-pix
+              reconcile the PIX DIMP daily input files against the database, restricted to the period if set
+  validate    parse and validate the CADOC 6334 input files
+  pix         generate the PIX DIMP daily files from the database, restricted to --from/--to if set;
+              --incremental only rewrites the days that changed since the last run;
+              duplicated records are left out, reported with --duplicate-report and flagged with --flag-duplicates
+  inspect     print the parsed records of the CADOC 6334 input files
+  layouts     print the file layouts and check that formatting and parsing are inverses
+
+Settings are resolved from flags, then CADOC_* environment variables,
+then the config file (--config or CADOC_CONFIG), then defaults.
+Run "cadoc <command> -h" to list the flags of a command.
+`
+
+// command describes a cadoc subcommand
+// flags selects the flags it accepts besides the common ones; commands needing the database also accept its flags
+type command struct {
+	needsDB bool
+	flags   flagGroup
+	run     func(cfg *config.Config, repo *adapter.GormAdapter) ([]*usecase.Result, error)
+}
+
+var commands = map[string]command{
+	"generate": {needsDB: true, run: generate},
+	"pix": {needsDB: true, flags: pixFlags | duplicateKeyFlags, run: func(cfg *config.Config, repo *adapter.GormAdapter) ([]*usecase.Result, error) {
+		uc := usecase.NewGenerateCase(repo, cfg.Paths.Out, cfg.ReferencePeriod(), &cfg.Institution, cfg.Document, cfg.Reports, cfg.OutputProfile())
+		from, to := cfg.PixRange()
+		return []*usecase.Result{uc.ExecuteAll(from, to, cfg.Pix.Incremental, cfg.Pix.Duplicates)}, nil
+	}},
+	"reconcile": {needsDB: true, flags: reconFlags, run: func(cfg *config.Config, repo *adapter.GormAdapter) ([]*usecase.Result, error) {
+		if cfg.Period == "" {
+			return nil, fmt.Errorf("reference period is required for reconcile")
+		}
+		inst, err := cfg.GetInstitution()
+		if err != nil {
+			return nil, err
+		}
+		uc := usecase.NewReconciliateCase(repo, cfg.Paths.In, cfg.ReferencePeriod(), inst, cfg.Document, cfg.Reports, cfg.Tolerances, cfg.LayoutVersion, cfg.MaxErrors, cfg.OutputProfile())
+		result, reconciliation := uc.ExecuteAll()
+		if cfg.Output.ReconciliationFile != "" {
+			if err := reconciliation.Export(cfg.Output.ReconciliationFile, cfg.Output.ReconciliationFormat); err != nil {
+				return nil, err
+			}
+			fmt.Printf("Reconciliation report written to %s\n", cfg.Output.ReconciliationFile)
+		}
+		return []*usecase.Result{result}, nil
+	}},
+	"reconcile-pix": {needsDB: true, flags: reconFlags | duplicateKeyFlags, run: func(cfg *config.Config, repo *adapter.GormAdapter) ([]*usecase.Result, error) {
+		uc := usecase.NewReconciliateCase(repo, cfg.Paths.In, cfg.ReferencePeriod(), &cfg.Institution, cfg.Document, nil, cfg.Tolerances, cfg.LayoutVersion, cfg.MaxErrors, cfg.OutputProfile())
+		result, reconciliation := uc.ExecutePix(cfg.Pix.Duplicates)
+		if cfg.Output.ReconciliationFile != "" {
+			if err := reconciliation.Export(cfg.Output.ReconciliationFile, cfg.Output.ReconciliationFormat); err != nil {
+				return nil, err
+			}
+			fmt.Printf("Reconciliation report written to %s\n", cfg.Output.ReconciliationFile)
+		}
+		return []*usecase.Result{result}, nil
+	}},
+	"validate": {run: func(cfg *config.Config, repo *adapter.GormAdapter) ([]*usecase.Result, error) {
+		uc := usecase.NewReconciliateCase(nil, cfg.Paths.In, cfg.ReferencePeriod(), &cfg.Institution, cfg.Document, cfg.Reports, nil, cfg.LayoutVersion, cfg.MaxErrors, cfg.OutputProfile())
+		return []*usecase.Result{uc.ValidateAll()}, nil
+	}},
+	"inspect": {run: func(cfg *config.Config, repo *adapter.GormAdapter) ([]*usecase.Result, error) {
+		uc := usecase.NewReconciliateCase(nil, cfg.Paths.In, cfg.ReferencePeriod(), &cfg.Institution, cfg.Document, cfg.Reports, nil, cfg.LayoutVersion, cfg.MaxErrors, cfg.OutputProfile())
+		return []*usecase.Result{uc.InspectAll()}, nil
+	}},
+	"layouts": {run: func(cfg *config.Config, repo *adapter.GormAdapter) ([]*usecase.Result, error) {
+		return []*usecase.Result{usecase.CheckLayouts()}, nil
+	}},
+}
+
+// generate generates the report package of one institution, or of every
+// configured institution when an institutions list is set
+func generate(cfg *config.Config, repo *adapter.GormAdapter) ([]*usecase.Result, error) {
+	if cfg.Period == "" {
+		return nil, fmt.Errorf("reference period is required for generate")
+	}
+	profile := cfg.OutputProfile()
+	if len(cfg.Institutions) == 0 {
+		inst, err := cfg.GetInstitution()
+		if err != nil {
+			return nil, err
+		}
+		uc := usecase.NewGenerateCase(repo, cfg.Paths.Out, cfg.ReferencePeriod(), inst, cfg.Document, cfg.Reports, profile)
+		return []*usecase.Result{uc.ExecuteAll2()}, nil
+	}
+	institutions, err := cfg.GetInstitutions()
+	if err != nil {
+		return nil, err
+	}
+	uc := usecase.NewGenerateCase(repo, cfg.Paths.Out, cfg.ReferencePeriod(), &domain.Institution{}, cfg.Document, cfg.Reports, profile)
+	return uc.ExecuteInstitutions(institutions), nil
+}
+
+// report prints the summary of the results and returns an error when any report failed
+func report(results []*usecase.Result) error {
+	fmt.Println("Summary:")
+	failures := 0
+	for _, r := range results {
+		fmt.Println(r)
+		failures += len(r.Failures())
+	}
+	if failures > 0 {
+		return fmt.Errorf("%d report(s) failed", failures)
+	}
+	return nil
+}
+
+// main function to run the cadoc command line
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "-h" || os.Args[1] == "--help" || os.Args[1] == "help" {
+		fmt.Print(usage)
+		return
+	}
+	name := os.Args[1]
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", name, usage)
+		os.Exit(2)
+	}
+	if err := run(name, cmd, os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "cadoc %s: %v\n", name, err)
+		os.Exit(1)
+	}
+}
+
+// run parses the command flags, resolves the configuration and executes the command
+func run(name string, cmd command, args []string) error {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	groups := cmd.flags
+	if cmd.needsDB {
+		groups |= dbFlags
+	}
+	flags := newFlags(fs, groups)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cfg, err := config.Load(flags.configPath)
+	if err != nil {
+		return err
+	}
+	flags.apply(fs, cfg)
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	domain.SetRoundingMode(cfg.RoundingMode())
+	domain.SetOverflowPolicy(cfg.OverflowPolicy())
+	domain.SetPixRate(cfg.OutputProfile().GetPixRate())
+	if err := cfg.LoadLayouts(); err != nil {
+		return err
+	}
+	if !cmd.needsDB {
+		results, err := cmd.run(cfg, nil)
+		if err != nil {
+			return err
+		}
+		return report(results)
+	}
+	if err := cfg.ValidateDatabase(); err != nil {
+		return err
+	}
+	repo, err := adapter.NewPostgresGormAdapter(adapter.PostgresConfig{
+		Host:     cfg.Database.Host,
+		Port:     cfg.Database.Port,
+		User:     cfg.Database.User,
+		Password: cfg.Database.Password,
+		DBName:   cfg.Database.Name,
+		SSLMode:  cfg.Database.SSLMode,
+	})
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %w", err)
+	}
+	defer repo.Close()
+	results, err := cmd.run(cfg, repo)
+	if err != nil {
+		return err
+	}
+	return report(results)
+}
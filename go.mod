@@ -3,9 +3,9 @@ module github.com/lavinas/cadoc6334
 go 1.25.3
 
 require (
-	github.com/ianlopshire/go-fixedwidth v0.10.0
 	github.com/shopspring/decimal v1.4.0
 	golang.org/x/text v0.21.0
+	gopkg.in/yaml.v3 v3.0.1
 	gorm.io/driver/postgres v1.6.0
 	gorm.io/gorm v1.31.0
 )
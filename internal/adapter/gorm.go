@@ -2,6 +2,8 @@ package adapter
 
 import (
 	"fmt"
+
+	"github.com/lavinas/cadoc6334/internal/port"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -62,6 +64,33 @@ func (g *GormAdapter) FindByPrimaryKey(dest interface{}, keyName string, keyValu
 	return g.db.First(dest, fmt.Sprintf("%s = ?", keyName), keyValue).Error
 }
 
+// Where returns a repository whose queries are all restricted by the given conditions
+// query follows gorm Where semantics: a SQL fragment with args or a column/value map
+func (g *GormAdapter) Where(query interface{}, args ...interface{}) port.Repository {
+	return &GormAdapter{db: g.db.Where(query, args...).Session(&gorm.Session{})}
+}
+
+// Select returns a repository whose queries all read the given columns instead of every column
+// columns is a SQL select list, e.g. "*, ctid::text AS row_id"
+func (g *GormAdapter) Select(columns string) port.Repository {
+	return &GormAdapter{db: g.db.Select(columns).Session(&gorm.Session{})}
+}
+
+// Save inserts the record, or updates every column of it when its primary key already exists
+func (g *GormAdapter) Save(value interface{}) error {
+	return g.db.Save(value).Error
+}
+
+// Update sets a column of the rows of the model table that match the given conditions
+// conditions follow gorm Where semantics; an update without conditions is refused by gorm
+func (g *GormAdapter) Update(model interface{}, column string, value interface{}, conditions ...interface{}) error {
+	query := g.db.Model(model)
+	if len(conditions) > 0 {
+		query = query.Where(conditions[0], conditions[1:]...)
+	}
+	return query.Update(column, value).Error
+}
+
 // Close closes the database connection
 func (g *GormAdapter) Close() error {
 	sqlDB, err := g.db.DB()
@@ -0,0 +1,399 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lavinas/cadoc6334/internal/domain"
+	"github.com/lavinas/cadoc6334/internal/port"
+	"github.com/lavinas/cadoc6334/internal/usecase"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// EnvConfig is the environment variable holding the config file path
+	EnvConfig = "CADOC_CONFIG"
+)
+
+// Config holds the runtime configuration of the cadoc command line.
+// Values are resolved with the following precedence (highest first):
+// command line flags, environment variables, config file, defaults.
+type Config struct {
+	Database      Database                         `yaml:"database"`
+	Paths         Paths                            `yaml:"paths"`
+	Period        string                           `yaml:"period"`
+	Document      string                           `yaml:"document"`
+	Reports       []string                         `yaml:"reports"`
+	Institution   domain.Institution               `yaml:"institution"`
+	Institutions  []domain.Institution             `yaml:"institutions"`
+	Output        Output                           `yaml:"output"`
+	Tolerances    []usecase.Tolerance              `yaml:"tolerances"`
+	Rounding      string                           `yaml:"rounding"`
+	Overflow      string                           `yaml:"overflow"`
+	LayoutFiles   []string                         `yaml:"layout_files"`
+	LayoutVersion string                           `yaml:"layout_version"`
+	MaxErrors     int                              `yaml:"max_errors"`
+	Profile       string                           `yaml:"profile"`
+	Profiles      map[string]usecase.OutputProfile `yaml:"profiles"`
+	Pix           Pix                              `yaml:"pix"`
+}
+
+// Pix holds the settings of the PIX DIMP file generation
+// From and To restrict the transaction dates generated, both inclusive, as YYYY-MM-DD; empty leaves the range open
+// Incremental only rewrites the days not yet generated or whose content changed, tracked on pix_generation
+// Duplicates sets how duplicated records are detected, reported and flagged
+type Pix struct {
+	From        string                  `yaml:"from"`
+	To          string                  `yaml:"to"`
+	Incremental bool                    `yaml:"incremental"`
+	Duplicates  usecase.DuplicatePolicy `yaml:"duplicates"`
+}
+
+// pixDate is the layout of the PIX generation range dates
+const pixDate = "2006-01-02"
+
+// Output holds the settings of the reports produced about a run
+// ReconciliationFile receives the reconciliation report; its format (json, csv or html)
+// is ReconciliationFormat or, when empty, the file extension
+type Output struct {
+	ReconciliationFile   string `yaml:"reconciliation_file"`
+	ReconciliationFormat string `yaml:"reconciliation_format"`
+}
+
+// Database holds the database connection settings
+type Database struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Name     string `yaml:"name"`
+	SSLMode  string `yaml:"sslmode"`
+}
+
+// Paths holds the input and output directories
+type Paths struct {
+	In  string `yaml:"in"`
+	Out string `yaml:"out"`
+}
+
+// Default returns the configuration used when nothing else is set
+func Default() *Config {
+	return &Config{
+		Database: Database{
+			Host:     "localhost",
+			Port:     5432,
+			User:     "root",
+			Password: "root",
+			Name:     "cadoc",
+			SSLMode:  "disable",
+		},
+		Paths: Paths{
+			In:  "./files/in",
+			Out: "./files/out",
+		},
+		Document: domain.DefaultDocument,
+	}
+}
+
+// Load builds a configuration from defaults, the given config file and the environment.
+// An empty path falls back to the CADOC_CONFIG environment variable; no file is read if both are empty.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if path == "" {
+		path = os.Getenv(EnvConfig)
+	}
+	if path != "" {
+		if err := cfg.LoadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.ApplyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadFile overlays the values of a YAML config file on the configuration
+func (c *Config) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("error parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// ApplyEnv overlays the values of CADOC_* environment variables on the configuration
+func (c *Config) ApplyEnv(lookup func(string) (string, bool)) error {
+	strs := map[string]*string{
+		"CADOC_DB_HOST":        &c.Database.Host,
+		"CADOC_DB_USER":        &c.Database.User,
+		"CADOC_DB_PASSWORD":    &c.Database.Password,
+		"CADOC_DB_NAME":        &c.Database.Name,
+		"CADOC_DB_SSLMODE":     &c.Database.SSLMode,
+		"CADOC_IN_DIR":         &c.Paths.In,
+		"CADOC_OUT_DIR":        &c.Paths.Out,
+		"CADOC_PERIOD":         &c.Period,
+		"CADOC_DOCUMENT":       &c.Document,
+		"CADOC_CNPJ_BASE":      &c.Institution.CNPJBase,
+		"CADOC_INST_NAME":      &c.Institution.Name,
+		"CADOC_RECON_FILE":     &c.Output.ReconciliationFile,
+		"CADOC_RECON_FORMAT":   &c.Output.ReconciliationFormat,
+		"CADOC_ROUNDING":       &c.Rounding,
+		"CADOC_OVERFLOW":       &c.Overflow,
+		"CADOC_PROFILE":        &c.Profile,
+		"CADOC_LAYOUT_VERSION": &c.LayoutVersion,
+		"CADOC_PIX_FROM":       &c.Pix.From,
+		"CADOC_PIX_TO":         &c.Pix.To,
+		"CADOC_PIX_DUP_REPORT": &c.Pix.Duplicates.Report,
+	}
+	for name, dest := range strs {
+		if v, ok := lookup(name); ok {
+			*dest = v
+		}
+	}
+	if v, ok := lookup("CADOC_DB_PORT"); ok {
+		port, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid CADOC_DB_PORT: %s", v)
+		}
+		c.Database.Port = port
+	}
+	if v, ok := lookup("CADOC_MAX_ERRORS"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid CADOC_MAX_ERRORS: %s", v)
+		}
+		c.MaxErrors = n
+	}
+	if v, ok := lookup("CADOC_PIX_INCREMENTAL"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid CADOC_PIX_INCREMENTAL: %s", v)
+		}
+		c.Pix.Incremental = b
+	}
+	if v, ok := lookup("CADOC_PIX_DUP_FLAG"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid CADOC_PIX_DUP_FLAG: %s", v)
+		}
+		c.Pix.Duplicates.Flag = b
+	}
+	if v, ok := lookup("CADOC_PIX_DUP_KEY"); ok {
+		c.Pix.Duplicates.Key = SplitList(v)
+	}
+	if v, ok := lookup("CADOC_REPORTS"); ok {
+		c.Reports = SplitList(v)
+	}
+	return nil
+}
+
+// Validate checks the configuration for missing or malformed values
+func (c *Config) Validate() error {
+	if c.Paths.In == "" {
+		return fmt.Errorf("input directory is not set")
+	}
+	if c.Paths.Out == "" {
+		return fmt.Errorf("output directory is not set")
+	}
+	if c.Period != "" {
+		if _, err := port.ParsePeriod(c.Period); err != nil {
+			return err
+		}
+	}
+	if err := c.validateReports(); err != nil {
+		return err
+	}
+	if c.Institution.CNPJBase != "" && len(c.Institutions) == 0 {
+		if err := c.Institution.Validate(); err != nil {
+			return err
+		}
+	}
+	seen := make(map[string]bool)
+	for _, inst := range c.Institutions {
+		if err := inst.Validate(); err != nil {
+			return err
+		}
+		if seen[inst.CNPJBase] {
+			return fmt.Errorf("duplicated institution cnpj base %s", inst.CNPJBase)
+		}
+		seen[inst.CNPJBase] = true
+	}
+	if _, err := domain.ParseRoundingMode(c.Rounding); err != nil {
+		return err
+	}
+	if _, err := domain.ParseOverflowPolicy(c.Overflow); err != nil {
+		return err
+	}
+	if c.MaxErrors < 0 {
+		return fmt.Errorf("invalid max errors: %d", c.MaxErrors)
+	}
+	for name, p := range c.Profiles {
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("output profile %s: %w", name, err)
+		}
+	}
+	if _, ok := c.Profiles[c.profileName()]; !ok && c.profileName() != usecase.DefaultProfile {
+		return fmt.Errorf("unknown output profile %q", c.Profile)
+	}
+	for _, t := range c.Tolerances {
+		if err := t.Validate(); err != nil {
+			return err
+		}
+	}
+	return c.validatePix()
+}
+
+// validatePix checks the dates of the PIX generation range and the duplicate key
+func (c *Config) validatePix() error {
+	var from, to time.Time
+	var err error
+	if c.Pix.From != "" {
+		if from, err = time.Parse(pixDate, c.Pix.From); err != nil {
+			return fmt.Errorf("invalid PIX from date %q: expected YYYY-MM-DD", c.Pix.From)
+		}
+	}
+	if c.Pix.To != "" {
+		if to, err = time.Parse(pixDate, c.Pix.To); err != nil {
+			return fmt.Errorf("invalid PIX to date %q: expected YYYY-MM-DD", c.Pix.To)
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		return fmt.Errorf("invalid PIX date range: %s is after %s", c.Pix.From, c.Pix.To)
+	}
+	return c.Pix.Duplicates.Validate()
+}
+
+// validateReports checks that the document and the selected reports are registered
+func (c *Config) validateReports() error {
+	if len(domain.GetReports(c.Document)) == 0 {
+		return fmt.Errorf("unknown document %q: expected one of %s", c.Document, strings.Join(domain.GetDocuments(), ", "))
+	}
+	for _, name := range c.Reports {
+		if _, err := domain.GetReport(c.Document, strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(name)), ".TXT")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetInstitution returns the institution of a single-institution run
+// with an institutions list, the institution cnpj base selects the entry
+func (c *Config) GetInstitution() (*domain.Institution, error) {
+	if len(c.Institutions) == 0 {
+		if err := c.Institution.Validate(); err != nil {
+			return nil, err
+		}
+		return &c.Institution, nil
+	}
+	for i := range c.Institutions {
+		if c.Institutions[i].CNPJBase == c.Institution.CNPJBase {
+			return &c.Institutions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("institution %q is not in the institutions list; use --cnpj-base to select one", c.Institution.CNPJBase)
+}
+
+// GetInstitutions returns the institutions of a multi-institution run
+// the institution cnpj base, when set, restricts the run to that entry
+func (c *Config) GetInstitutions() ([]domain.Institution, error) {
+	if c.Institution.CNPJBase == "" {
+		return c.Institutions, nil
+	}
+	inst, err := c.GetInstitution()
+	if err != nil {
+		return nil, err
+	}
+	return []domain.Institution{*inst}, nil
+}
+
+// ValidateDatabase checks the database settings needed to open a connection
+func (c *Config) ValidateDatabase() error {
+	if c.Database.Host == "" {
+		return fmt.Errorf("database host is not set")
+	}
+	if c.Database.Port <= 0 {
+		return fmt.Errorf("invalid database port: %d", c.Database.Port)
+	}
+	if c.Database.Name == "" {
+		return fmt.Errorf("database name is not set")
+	}
+	return nil
+}
+
+// ReferencePeriod returns the configured reference period
+// a zero period is returned when none is configured
+func (c *Config) ReferencePeriod() port.Period {
+	period, _ := port.ParsePeriod(c.Period)
+	return period
+}
+
+// PixRange returns the configured range of PIX transaction dates, both inclusive
+// a zero date is returned for an open side of the range
+func (c *Config) PixRange() (time.Time, time.Time) {
+	from, _ := time.Parse(pixDate, c.Pix.From)
+	to, _ := time.Parse(pixDate, c.Pix.To)
+	return from, to
+}
+
+// RoundingMode returns the configured rounding mode of amounts and rates
+func (c *Config) RoundingMode() domain.RoundingMode {
+	mode, _ := domain.ParseRoundingMode(c.Rounding)
+	return mode
+}
+
+// OverflowPolicy returns the configured policy for text wider than its field
+func (c *Config) OverflowPolicy() domain.OverflowPolicy {
+	policy, _ := domain.ParseOverflowPolicy(c.Overflow)
+	return policy
+}
+
+// OutputProfile returns the selected output profile
+// the default profile writes LF terminated ISO-8859-1 lines unless the profiles redefine it
+func (c *Config) OutputProfile() usecase.OutputProfile {
+	return c.Profiles[c.profileName()]
+}
+
+// profileName returns the name of the selected output profile
+func (c *Config) profileName() string {
+	if c.Profile == "" {
+		return usecase.DefaultProfile
+	}
+	return c.Profile
+}
+
+// LoadLayouts registers the layout versions of the configured layout files
+func (c *Config) LoadLayouts() error {
+	for _, path := range c.LayoutFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading layout file: %w", err)
+		}
+		var spec domain.LayoutSpec
+		if err := yaml.Unmarshal(data, &spec); err != nil {
+			return fmt.Errorf("error parsing layout file %s: %w", path, err)
+		}
+		if _, err := domain.RegisterLayout(spec); err != nil {
+			return fmt.Errorf("layout file %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// SplitList splits a comma separated list, dropping empty items
+func SplitList(s string) []string {
+	var ret []string
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
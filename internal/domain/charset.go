@@ -0,0 +1,172 @@
+package domain
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Charset represents the character encoding of the files
+type Charset string
+
+const (
+	CharsetLatin1      Charset = "iso-8859-1"
+	CharsetWindows1252 Charset = "windows-1252"
+	CharsetASCII       Charset = "ascii"
+	CharsetUTF8        Charset = "utf-8"
+)
+
+// Unrepresentable represents what happens to characters the charset cannot represent
+type Unrepresentable string
+
+const (
+	// UnrepresentableFail rejects the line
+	UnrepresentableFail Unrepresentable = "fail"
+	// UnrepresentableTransliterate replaces each character by a similar one, e.g. "€" by "E" or "—" by "-", or by "?"
+	UnrepresentableTransliterate Unrepresentable = "transliterate"
+	// UnrepresentableReplace replaces each character by "?"
+	UnrepresentableReplace Unrepresentable = "replace"
+	// UnrepresentableStripAccents removes the accents of every character and rejects what is still unrepresentable
+	UnrepresentableStripAccents Unrepresentable = "strip_accents"
+)
+
+// replacement is written in place of characters without transliteration
+const replacement = '?'
+
+// transliterations maps common characters missing from the single byte charsets to one similar character,
+// so records keep their length
+var transliterations = map[rune]rune{
+	'‘': '\'', '’': '\'', '‚': '\'', '‹': '<', '›': '>',
+	'“': '"', '”': '"', '„': '"', '«': '"', '»': '"',
+	'–': '-', '—': '-', '…': '.', '•': '*', '€': 'E',
+	'Œ': 'O', 'œ': 'o', 'Š': 'S', 'š': 's', 'Ž': 'Z', 'ž': 'z', 'Ÿ': 'Y',
+	'ß': 's', 'Æ': 'A', 'æ': 'a', 'Ø': 'O', 'ø': 'o', 'Ð': 'D', 'ð': 'd', 'Þ': 'T', 'þ': 't',
+	' ': ' ', 'º': 'o', 'ª': 'a',
+}
+
+// Encoding is a charset with its policy for unrepresentable characters
+// it applies to every file written or read
+type Encoding struct {
+	Charset         Charset
+	Unrepresentable Unrepresentable
+}
+
+// DefaultEncoding writes ISO-8859-1 and rejects unrepresentable characters
+var DefaultEncoding = Encoding{Charset: CharsetLatin1, Unrepresentable: UnrepresentableFail}
+
+// NewEncoding creates a new Encoding instance from a charset and policy name
+// empty names mean ISO-8859-1 and fail
+func NewEncoding(charset string, policy string) (Encoding, error) {
+	enc := DefaultEncoding
+	switch strings.ToLower(charset) {
+	case "", "iso-8859-1", "iso8859-1", "latin1":
+	case "windows-1252", "cp1252":
+		enc.Charset = CharsetWindows1252
+	case "ascii", "us-ascii":
+		enc.Charset = CharsetASCII
+	case "utf-8", "utf8":
+		enc.Charset = CharsetUTF8
+	default:
+		return Encoding{}, fmt.Errorf("invalid encoding %q: expected iso-8859-1, windows-1252, ascii or utf-8", charset)
+	}
+	switch Unrepresentable(policy) {
+	case "", UnrepresentableFail:
+	case UnrepresentableTransliterate, UnrepresentableReplace, UnrepresentableStripAccents:
+		enc.Unrepresentable = Unrepresentable(policy)
+	default:
+		return Encoding{}, fmt.Errorf("invalid unrepresentable character policy %q: expected fail, transliterate, replace or strip_accents", policy)
+	}
+	return enc, nil
+}
+
+// String returns the charset and policy of the encoding
+func (e Encoding) String() string {
+	return fmt.Sprintf("%s (%s)", e.Charset, e.Unrepresentable)
+}
+
+// Representable reports whether the charset can represent a character
+func (e Encoding) Representable(r rune) bool {
+	switch e.Charset {
+	case CharsetASCII:
+		return r < utf8.RuneSelf
+	case CharsetUTF8:
+		return r != utf8.RuneError && utf8.ValidRune(r)
+	case CharsetWindows1252:
+		_, ok := charmap.Windows1252.EncodeRune(r)
+		return ok
+	}
+	_, ok := charmap.ISO8859_1.EncodeRune(r)
+	return ok
+}
+
+// Apply applies the policy to the characters the charset cannot represent
+// every character is replaced by at most one, so fixed-width records keep their length
+// rejected characters are reported as a *FieldError on their position
+func (e Encoding) Apply(s string) (string, error) {
+	if e.Unrepresentable == UnrepresentableStripAccents {
+		var err error
+		if s, err = RemoveAccents(s); err != nil {
+			return "", err
+		}
+	}
+	var sb strings.Builder
+	pos := 0
+	for _, r := range s {
+		pos++
+		if e.Representable(r) {
+			sb.WriteRune(r)
+			continue
+		}
+		switch e.Unrepresentable {
+		case UnrepresentableTransliterate:
+			sb.WriteRune(e.transliterate(r))
+		case UnrepresentableReplace:
+			sb.WriteRune(replacement)
+		default:
+			return "", &FieldError{Start: pos, End: pos, Err: fmt.Errorf("character %q is not representable in %s", r, e.Charset)}
+		}
+	}
+	return sb.String(), nil
+}
+
+// transliterate returns a representable character similar to an unrepresentable one
+func (e Encoding) transliterate(r rune) rune {
+	if t, ok := transliterations[r]; ok && e.Representable(t) {
+		return t
+	}
+	if base, _ := utf8.DecodeRuneInString(norm.NFD.String(string(r))); base != r && e.Representable(base) {
+		return base
+	}
+	return replacement
+}
+
+// Encode applies the policy to a line and converts it to the charset
+func (e Encoding) Encode(s string) ([]byte, error) {
+	s, err := e.Apply(s)
+	if err != nil {
+		return nil, err
+	}
+	switch e.Charset {
+	case CharsetUTF8, CharsetASCII:
+		return []byte(s), nil
+	case CharsetWindows1252:
+		return charmap.Windows1252.NewEncoder().Bytes([]byte(s))
+	}
+	return charmap.ISO8859_1.NewEncoder().Bytes([]byte(s))
+}
+
+// NewReader returns a reader converting the charset to UTF-8
+// ASCII and UTF-8 are read as is; their invalid bytes are rejected as unrepresentable
+func (e Encoding) NewReader(r io.Reader) io.Reader {
+	switch e.Charset {
+	case CharsetWindows1252:
+		return charmap.Windows1252.NewDecoder().Reader(r)
+	case CharsetLatin1:
+		return charmap.ISO8859_1.NewDecoder().Reader(r)
+	}
+	return r
+}
@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEncodingApply(t *testing.T) {
+	tests := []struct {
+		charset string
+		policy  string
+		in      string
+		want    string
+		errPos  int
+	}{
+		{charset: "iso-8859-1", policy: "fail", in: "Preço ok", want: "Preço ok"},
+		{charset: "iso-8859-1", policy: "fail", in: "Preço €10", errPos: 7},
+		{charset: "iso-8859-1", policy: "transliterate", in: "Preço €10 — “ok”", want: "Preço E10 - \"ok\""},
+		{charset: "iso-8859-1", policy: "transliterate", in: "Ωmega", want: "?mega"},
+		{charset: "iso-8859-1", policy: "replace", in: "Preço €10", want: "Preço ?10"},
+		{charset: "iso-8859-1", policy: "strip_accents", in: "Preço São", want: "Preco Sao"},
+		{charset: "iso-8859-1", policy: "strip_accents", in: "Preço €10", errPos: 7},
+		{charset: "windows-1252", policy: "fail", in: "Preço €10", want: "Preço €10"},
+		{charset: "ascii", policy: "fail", in: "Preço", errPos: 4},
+		{charset: "ascii", policy: "transliterate", in: "Preço Straße ºC", want: "Preco Strase oC"},
+		{charset: "ascii", policy: "replace", in: "Preço", want: "Pre?o"},
+		{charset: "ascii", policy: "strip_accents", in: "Preço", want: "Preco"},
+		{charset: "utf-8", policy: "fail", in: "Preço €10 Ω", want: "Preço €10 Ω"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.charset+"/"+tt.policy+"/"+tt.in, func(t *testing.T) {
+			enc, err := NewEncoding(tt.charset, tt.policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := enc.Apply(tt.in)
+			if tt.errPos > 0 {
+				var fe *FieldError
+				if !errors.As(err, &fe) || fe.Start != tt.errPos {
+					t.Fatalf("Apply(%q) = %q, %v, expected a field error on position %d", tt.in, got, err, tt.errPos)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply(%q) error: %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("Apply(%q) = %q, expected %q", tt.in, got, tt.want)
+			}
+			if len([]rune(got)) != len([]rune(tt.in)) {
+				t.Errorf("Apply(%q) changed the length to %d", tt.in, len([]rune(got)))
+			}
+		})
+	}
+}
+
+func TestNewEncodingInvalid(t *testing.T) {
+	if _, err := NewEncoding("ebcdic", ""); err == nil {
+		t.Error("NewEncoding accepted charset ebcdic")
+	}
+	if _, err := NewEncoding("", "drop"); err == nil {
+		t.Error("NewEncoding accepted policy drop")
+	}
+}
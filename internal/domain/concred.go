@@ -1,28 +1,36 @@
 package domain
 
 import (
-	"bufio"
 	"fmt"
-	"os"
 
-	"github.com/ianlopshire/go-fixedwidth"
 	"github.com/lavinas/cadoc6334/internal/port"
-	"golang.org/x/text/encoding/charmap"
+	"github.com/shopspring/decimal"
 )
 
 // Conccred represents the Conccred data model.
 type Conccred struct {
-	Year                       int64   `fixed:"1,4" gorm:"column:ano"`
-	Quarter                    int64   `fixed:"5,5" gorm:"column:trimestre"`
-	Brand                      int64   `fixed:"6,7" gorm:"column:bandeira"`
-	Function                   string  `fixed:"8,8" gorm:"column:funcao"`
-	CredentialedEstablishments int64   `fixed:"9,17" gorm:"column:quantidade_estabelecimentos_credenciados"`
-	ActiveEstablishments       int64   `fixed:"18,26" gorm:"column:quantidade_estabelecimentos_ativos"`
-	TransactionValue           float64 `gorm:"column:valor_transacoes"`
-	TransactionValueInt        int64   `fixed:"27,41"`
-	TransactionQuantity        int64   `fixed:"42,53" gorm:"column:quantidade_transacoes"`
+	Year                       int64           `gorm:"column:ano"`
+	Quarter                    int64           `gorm:"column:trimestre"`
+	Brand                      int64           `gorm:"column:bandeira"`
+	Function                   string          `gorm:"column:funcao"`
+	CredentialedEstablishments int64           `gorm:"column:quantidade_estabelecimentos_credenciados"`
+	ActiveEstablishments       int64           `gorm:"column:quantidade_estabelecimentos_ativos"`
+	TransactionValue           decimal.Decimal `gorm:"column:valor_transacoes"`
+	TransactionQuantity        int64           `gorm:"column:quantidade_transacoes"`
 }
 
+// conccredLayout describes the CONCCRED.TXT record
+var conccredLayout = registerLayout("CONCCRED", &Conccred{},
+	numericAt("Year", 1, 4),
+	numericAt("Quarter", 5, 1).oneOf("1", "2", "3", "4"),
+	numericAt("Brand", 6, 2),
+	textAt("Function", 8, 1),
+	numericAt("CredentialedEstablishments", 9, 9),
+	numericAt("ActiveEstablishments", 18, 9),
+	decimalAt("TransactionValue", 27, 15, 2),
+	numericAt("TransactionQuantity", 42, 12),
+)
+
 // NewConccred creates a new Conccred instance.
 func NewConccred() *Conccred {
 	return &Conccred{}
@@ -35,18 +43,7 @@ func (c *Conccred) GetName() string {
 
 // MarshalFixedWidth marshals the Conccred struct into a fixed-width format.
 func (c *Conccred) Format() string {
-	ret := ""
-	ret += fmt.Sprintf("%04d", c.Year)
-	ret += fmt.Sprintf("%01d", c.Quarter)
-	ret += fmt.Sprintf("%02d", c.Brand)
-	ret += fmt.Sprintf("%01s", c.Function)
-	ret += fmt.Sprintf("%09d", c.CredentialedEstablishments)
-	ret += fmt.Sprintf("%09d", c.ActiveEstablishments)
-	// Convert TransactionValue to int representation
-	c.TransactionValueInt = int64(c.TransactionValue*100 + 0.5)
-	ret += fmt.Sprintf("%015d", c.TransactionValueInt)
-	ret += fmt.Sprintf("%012d", c.TransactionQuantity)
-	return ret
+	return conccredLayout.Format(c)
 }
 
 // Validate validates the Conccred header information.
@@ -69,13 +66,13 @@ func (c *Conccred) Validate() error {
 	if c.ActiveEstablishments <= 0 {
 		return fmt.Errorf("invalid number of active establishments in header")
 	}
-	if c.TransactionValue <= 0 {
+	if !c.TransactionValue.IsPositive() {
 		return fmt.Errorf("invalid transaction value in header")
 	}
 	if c.TransactionQuantity <= 0 {
 		return fmt.Errorf("invalid transaction quantity in header")
 	}
-	return nil
+	return conccredLayout.Validate(c)
 }
 
 // TableName returns the table name for the Conccred struct.
@@ -89,14 +86,17 @@ func (c *Conccred) GetKey() string {
 }
 
 // FindAll retrieves all Conccred records.
-func (c *Conccred) GetDB(repo port.Repository) (map[string]port.Report, error) {
+func (c *Conccred) GetDB(repo port.Repository, period port.Period) (map[string]port.Report, error) {
 	var records []*Conccred
-	err := repo.FindAll(&records, 0, 0, "")
+	err := repo.FindAll(&records, 0, 0, "", "ano = ? AND trimestre = ?", period.Year, period.Quarter)
 	if err != nil {
 		return nil, err
 	}
 	ret := make(map[string]port.Report)
 	for _, r := range records {
+		if err := period.Check(r.Year, r.Quarter); err != nil {
+			return nil, fmt.Errorf("key %s: %w", r.GetKey(), err)
+		}
 		ret[r.GetKey()] = r
 	}
 	return ret, nil
@@ -104,66 +104,55 @@ func (c *Conccred) GetDB(repo port.Repository) (map[string]port.Report, error) {
 
 // Parse parses a line of text into a Conccred struct.
 func (c *Conccred) Parse(line string) (*Conccred, error) {
-	err := fixedwidth.Unmarshal([]byte(line), c)
-	if err != nil {
+	if err := conccredLayout.Parse(line, c); err != nil {
 		return nil, err
 	}
-	// Convert TransactionValueInt back to float64
-	c.TransactionValue = float64(float64(c.TransactionValueInt) / float64(100))
 	return c, nil
 }
 
 // String returns a string representation of the Conccred struct.
 func (c *Conccred) String() string {
-	return fmt.Sprintf("Year: %d, Quarter: %d, Brand: %d, Function: %s, CredentialedEstablishments: %d, ActiveEstablishments: %d, TransactionValue: %.2f, TransactionQuantity: %d",
-		c.Year, c.Quarter, c.Brand, c.Function, c.CredentialedEstablishments, c.ActiveEstablishments, c.TransactionValue, c.TransactionQuantity)
+	return fmt.Sprintf("Year: %d, Quarter: %d, Brand: %d, Function: %s, CredentialedEstablishments: %d, ActiveEstablishments: %d, TransactionValue: %s, TransactionQuantity: %d",
+		c.Year, c.Quarter, c.Brand, c.Function, c.CredentialedEstablishments, c.ActiveEstablishments, c.TransactionValue.StringFixed(2), c.TransactionQuantity)
+}
+
+// GetFields returns the Conccred fields for field level comparison
+func (c *Conccred) GetFields() []port.Field {
+	return []port.Field{
+		intField("Year", c.Year),
+		intField("Quarter", c.Quarter),
+		intField("Brand", c.Brand),
+		textField("Function", c.Function),
+		intField("CredentialedEstablishments", c.CredentialedEstablishments),
+		intField("ActiveEstablishments", c.ActiveEstablishments),
+		amountField("TransactionValue", c.TransactionValue),
+		intField("TransactionQuantity", c.TransactionQuantity),
+	}
 }
 
 // ParseConccredFile parses a file containing Conccred records.
-func (c *Conccred) ParseConccredFile(filename string) ([]*Conccred, error) {
-	file, err := os.Open(filename)
+func (c *Conccred) ParseConccredFile(filename string, opts port.ParseOptions) ([]*Conccred, error) {
+	reader, err := openFile(filename, "CONCCRED", opts)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
-	decoder := charmap.ISO8859_1.NewDecoder()
-	decodedReader := decoder.Reader(file)
-	scanner := bufio.NewScanner(decodedReader)
-	// read header
-	if !scanner.Scan() {
-		return nil, fmt.Errorf("file is empty")
-	}
-	headerLine := scanner.Text()
-	header := &RankingHeader{}
-	_, err = header.Parse(headerLine)
-	if err != nil {
-		return nil, fmt.Errorf("error parsing header: %w", err)
-	}
 	// read records
 	var records []*Conccred
-	var count int64 = 0
-	for scanner.Scan() {
-		line := scanner.Text()
-		var c Conccred
-		record, err := c.Parse(line)
-		if err != nil {
-			return nil, err
+	for reader.Next() {
+		record := &Conccred{}
+		if reader.Parse(record) && reader.CheckPeriod(record.Year, record.Quarter) {
+			records = append(records, record)
 		}
-		records = append(records, record)
-		count++
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
 	}
-	if err := header.Validate("CONCCRED", count); err != nil {
+	if err := reader.Close(); err != nil {
 		return nil, err
 	}
 	return records, nil
 }
 
 // GetParsedFile retrieves and maps Conccred records from a file.
-func (c *Conccred) GetParsedFile(filename string) (map[string]port.Report, error) {
-	fileConccred, err := c.ParseConccredFile(filename)
+func (c *Conccred) GetParsedFile(filename string, opts port.ParseOptions) (map[string]port.Report, error) {
+	fileConccred, err := c.ParseConccredFile(filename, opts)
 	if err != nil {
 		return nil, err
 	}
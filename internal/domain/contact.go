@@ -1,26 +1,33 @@
 package domain
 
 import (
-	"bufio"
 	"fmt"
-	"os"
 
-	"github.com/ianlopshire/go-fixedwidth"
 	"github.com/lavinas/cadoc6334/internal/port"
-	"golang.org/x/text/encoding/charmap"
 )
 
 // Contact represents the Contact data model.
 type Contact struct {
-	Year        int64  `fixed:"1,4" gorm:"column:ano"`
-	Quarter     int64  `fixed:"5,5" gorm:"column:trimestre"`
-	ContactType string `fixed:"6,6" gorm:"column:tipocontato"`
-	Name        string `fixed:"7,56" gorm:"column:nome"`
-	Position    string `fixed:"57,106" gorm:"column:cargo"`
-	Phone       string `fixed:"107,156" gorm:"column:numerotelefone"`
-	Email       string `fixed:"157,206" gorm:"column:email"`
+	Year        int64  `gorm:"column:ano"`
+	Quarter     int64  `gorm:"column:trimestre"`
+	ContactType string `gorm:"column:tipocontato"`
+	Name        string `gorm:"column:nome"`
+	Position    string `gorm:"column:cargo"`
+	Phone       string `gorm:"column:numerotelefone"`
+	Email       string `gorm:"column:email"`
 }
 
+// contactLayout describes the CONTATOS.TXT record
+var contactLayout = registerLayout("CONTATOS", &Contact{},
+	numericAt("Year", 1, 4),
+	numericAt("Quarter", 5, 1).oneOf("1", "2", "3", "4"),
+	textAt("ContactType", 6, 1),
+	textAt("Name", 7, 50),
+	textAt("Position", 57, 50),
+	textAt("Phone", 107, 50),
+	textAt("Email", 157, 50),
+)
+
 // NewContact creates a new Contact instance.
 func NewContact() *Contact {
 	return &Contact{}
@@ -33,15 +40,7 @@ func (c *Contact) GetName() string {
 
 // Format marshals the Contact struct into a fixed-width format.
 func (c *Contact) Format() string {
-	ret := ""
-	ret += fmt.Sprintf("%04d", c.Year)
-	ret += fmt.Sprintf("%01d", c.Quarter)
-	ret += fmt.Sprintf("%01s", c.ContactType)
-	ret += fmt.Sprintf("%-50s", c.Name)
-	ret += fmt.Sprintf("%-50s", c.Position)
-	ret += fmt.Sprintf("%-50s", c.Phone)
-	ret += fmt.Sprintf("%-50s", c.Email)
-	return ret
+	return contactLayout.Format(c)
 }
 
 // Validate validates the Contact header information.
@@ -58,7 +57,7 @@ func (c *Contact) Validate() error {
 	if c.Email == "" {
 		return fmt.Errorf("invalid email in header")
 	}
-	return nil
+	return contactLayout.Validate(c)
 }
 
 // TableName returns the table name for the Contact struct.
@@ -72,14 +71,17 @@ func (c *Contact) GetKey() string {
 }
 
 // GetDB returns the database connection.
-func (c *Contact) GetDB(repo port.Repository) (map[string]port.Report, error) {
+func (c *Contact) GetDB(repo port.Repository, period port.Period) (map[string]port.Report, error) {
 	var records []*Contact
-	err := repo.FindAll(&records, 0, 0, "")
+	err := repo.FindAll(&records, 0, 0, "", "ano = ? AND trimestre = ?", period.Year, period.Quarter)
 	if err != nil {
 		return nil, err
 	}
 	ret := make(map[string]port.Report)
 	for _, r := range records {
+		if err := period.Check(r.Year, r.Quarter); err != nil {
+			return nil, fmt.Errorf("key %s: %w", r.GetKey(), err)
+		}
 		ret[r.GetKey()] = r
 	}
 	return ret, nil
@@ -87,8 +89,7 @@ func (c *Contact) GetDB(repo port.Repository) (map[string]port.Report, error) {
 
 // Parse parses the Contact data from a fixed-width file.
 func (c *Contact) Parse(line string) (*Contact, error) {
-	err := fixedwidth.Unmarshal([]byte(line), c)
-	if err != nil {
+	if err := contactLayout.Parse(line, c); err != nil {
 		return nil, err
 	}
 	return c, nil
@@ -100,52 +101,42 @@ func (c *Contact) String() string {
 		c.Year, c.Quarter, c.ContactType, c.Name, c.Position, c.Phone, c.Email)
 }
 
-// ParseContactFile parses a file containing Contact records.
-func (c *Contact) ParseContactFile(filePath string) ([]*Contact, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, err
+// GetFields returns the Contact fields for field level comparison
+func (c *Contact) GetFields() []port.Field {
+	return []port.Field{
+		intField("Year", c.Year),
+		intField("Quarter", c.Quarter),
+		textField("ContactType", c.ContactType),
+		textField("Name", c.Name),
+		textField("Position", c.Position),
+		textField("Phone", c.Phone),
+		textField("Email", c.Email),
 	}
-	defer file.Close()
-	var contacts []*Contact
-	decoder := charmap.ISO8859_1.NewDecoder()
-	decodedReader := decoder.Reader(file)
+}
 
-	scanner := bufio.NewScanner(decodedReader)
-	// read header
-	if !scanner.Scan() {
-		return nil, fmt.Errorf("file is empty")
-	}
-	headerLine := scanner.Text()
-	header := &RankingHeader{}
-	_, err = header.Parse(headerLine)
+// ParseContactFile parses a file containing Contact records.
+func (c *Contact) ParseContactFile(filePath string, opts port.ParseOptions) ([]*Contact, error) {
+	reader, err := openFile(filePath, "CONTATOS", opts)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing header: %w", err)
+		return nil, err
 	}
 	// read contacts
-	count := int64(0)
-	for scanner.Scan() {
-		line := scanner.Text()
+	var contacts []*Contact
+	for reader.Next() {
 		contact := NewContact()
-		err := fixedwidth.Unmarshal([]byte(line), contact)
-		if err != nil {
-			return nil, err
+		if reader.Parse(contact) && reader.CheckPeriod(contact.Year, contact.Quarter) {
+			contacts = append(contacts, contact)
 		}
-		contacts = append(contacts, contact)
-		count++
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
 	}
-	if err := header.Validate("CONTATOS", count); err != nil {
+	if err := reader.Close(); err != nil {
 		return nil, err
 	}
 	return contacts, nil
 }
 
 // GetParsedFile retrieves and maps Conccred records from a file.
-func (c *Contact) GetParsedFile(filename string) (map[string]port.Report, error) {
-	fileConccred, err := c.ParseContactFile(filename)
+func (c *Contact) GetParsedFile(filename string, opts port.ParseOptions) (map[string]port.Report, error) {
+	fileConccred, err := c.ParseContactFile(filename, opts)
 	if err != nil {
 		return nil, err
 	}
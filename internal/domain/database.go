@@ -1,7 +1,6 @@
 package domain
 
 import (
-	"fmt"
 	"time"
 
 	"github.com/lavinas/cadoc6334/internal/port"
@@ -9,34 +8,42 @@ import (
 
 // Database struct represents a database connection (placeholder)
 type Database struct {
-	FileName string `fixed:"1,8"`
-	DateStr  string `fixed:"9,16"`
-	Acquirer string `fixed:"17,24"`
-	BaseDate string `fixed:"25,38"`
+	FileName string
+	DateStr  string
+	Acquirer string
+	BaseDate string
 }
 
-// NewDatabase creates a new Database instance
-func NewDatabase() *Database {
+// databaseLayout describes the DATABASE.TXT record
+var databaseLayout = registerLayout("DATABASE", &Database{},
+	textAt("FileName", 1, 8),
+	textAt("DateStr", 9, 8),
+	textAt("Acquirer", 17, 8),
+	textAt("BaseDate", 25, 6),
+)
+
+// NewDatabase creates a new Database instance for the acquirer stamped with the reference period
+func NewDatabase(acquirer string, period port.Period) *Database {
 	return &Database{
 		FileName: "DATABASE",
 		DateStr:  time.Now().Format("20060102"),
-		Acquirer: "47377613",
-		BaseDate: "202509",
+		Acquirer: acquirer,
+		BaseDate: period.BaseDate(),
 	}
 }
 
 // Validate validates the Database information.
 func (d *Database) Validate() error {
-	return nil
+	return databaseLayout.Validate(d)
 }
 
 // GetParsedFile returns parsed file data.
-func (d *Database) GetParsedFile(filename string) (map[string]port.Report, error) {
+func (d *Database) GetParsedFile(filename string, opts port.ParseOptions) (map[string]port.Report, error) {
 	return nil, nil
 }
 
 // GetDB returns the database connection.
-func (d *Database) GetDB(repo port.Repository) (map[string]port.Report, error) {
+func (d *Database) GetDB(repo port.Repository, period port.Period) (map[string]port.Report, error) {
 	return nil, nil
 }
 
@@ -45,14 +52,19 @@ func (d *Database) String() string {
 	return ""
 }
 
+// GetFields returns the Database fields for field level comparison
+func (d *Database) GetFields() []port.Field {
+	return []port.Field{
+		textField("FileName", d.FileName),
+		textField("DateStr", d.DateStr),
+		textField("Acquirer", d.Acquirer),
+		textField("BaseDate", d.BaseDate),
+	}
+}
+
 // Format marshals the Database struct into a fixed-width format.
 func (d *Database) Format() string {
-	ret := ""
-	ret += fmt.Sprintf("%-8s", d.FileName)
-	ret += fmt.Sprintf("%-8s", d.DateStr)
-	ret += fmt.Sprintf("%-8s", d.Acquirer)
-	ret += fmt.Sprintf("%-6s", d.BaseDate)
-	return ret
+	return databaseLayout.Format(d)
 }
 
 // GetName gets name of the report
@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"strconv"
+
+	"github.com/lavinas/cadoc6334/internal/port"
+	"github.com/shopspring/decimal"
+)
+
+// textField creates a text report field
+func textField(name string, value string) port.Field {
+	return port.Field{Name: name, Kind: port.FieldText, Value: value}
+}
+
+// intField creates an integer report field
+func intField(name string, value int64) port.Field {
+	return port.Field{Name: name, Kind: port.FieldInteger, Value: strconv.FormatInt(value, 10), Number: decimal.NewFromInt(value)}
+}
+
+// amountField creates a monetary report field rounded to two decimal places
+func amountField(name string, value decimal.Decimal) port.Field {
+	d := roundDecimal(value, 2)
+	return port.Field{Name: name, Kind: port.FieldAmount, Value: d.StringFixed(2), Number: d}
+}
+
+// rateField creates a rate report field rounded to two decimal places
+func rateField(name string, value decimal.Decimal) port.Field {
+	d := roundDecimal(value, 2)
+	return port.Field{Name: name, Kind: port.FieldRate, Value: d.StringFixed(2), Number: d}
+}
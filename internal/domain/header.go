@@ -4,59 +4,143 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/ianlopshire/go-fixedwidth"
+	"github.com/lavinas/cadoc6334/internal/port"
 )
 
+// HeaderError reports a header record that is missing, malformed or inconsistent with its file
+// it tells a broken header apart from broken data records
+type HeaderError struct {
+	File  string
+	Field string
+	Err   error
+}
+
+// Error returns the description of the header error
+func (e *HeaderError) Error() string {
+	ret := "invalid header"
+	if e.File != "" {
+		ret = fmt.Sprintf("invalid %s header", e.File)
+	}
+	if e.Field != "" {
+		ret += ": " + e.Field
+	}
+	return fmt.Sprintf("%s: %v", ret, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *HeaderError) Unwrap() error {
+	return e.Err
+}
+
+// newHeaderError creates a new HeaderError instance
+func newHeaderError(file string, field string, format string, args ...interface{}) *HeaderError {
+	return &HeaderError{File: file, Field: field, Err: fmt.Errorf(format, args...)}
+}
+
 type RankingHeader struct {
-	FileName string `fixed:"1,8"`
-	DateStr  string `fixed:"9,16"`
+	FileName string
+	DateStr  string
 	Date     time.Time
-	Acquirer string `fixed:"17,24"`
-	Lines    int64  `fixed:"25,38"`
+	Acquirer string
+	Lines    int64
 }
 
+// headerLayout describes the header record of every CADOC file
+var headerLayout = registerLayout("HEADER", &RankingHeader{},
+	textAt("FileName", 1, 8),
+	textAt("DateStr", 9, 8),
+	textAt("Acquirer", 17, 8),
+	numericAt("Lines", 25, 8),
+)
+
 // GetNewRankingHeader creates a new RankingHeader instance.
-func NewHeader(filename string, lines int64) *RankingHeader {
+// acquirer is the CNPJ base of the institution filing the report
+func NewHeader(filename string, acquirer string, lines int64) *RankingHeader {
 	return &RankingHeader{
 		FileName: filename,
 		DateStr:  time.Now().Format("20060102"),
-		Acquirer: "47377613",
+		Acquirer: acquirer,
 		Lines:    lines,
 	}
 }
 
 // Format marshals the RankingHeader struct into a fixed-width format.
 func (rh *RankingHeader) Format() string {
-	ret := ""
-	ret += fmt.Sprintf("%-8s", rh.FileName)
-	ret += fmt.Sprintf("%-8s", rh.DateStr)
-	ret += fmt.Sprintf("%-8s", rh.Acquirer)
-	ret += fmt.Sprintf("%08d", rh.Lines)
-	return ret
+	return headerLayout.Format(rh)
+}
+
+// FormatLine marshals the RankingHeader struct into a fixed-width line checked for the file
+func (rh *RankingHeader) FormatLine(enc Encoding) (string, error) {
+	return headerLayout.FormatLine(rh, enc)
 }
 
 // Parse parses a line of text into a RankingHeader struct
-func (rh *RankingHeader) Parse(line string) (*RankingHeader, error) {
-	err := fixedwidth.Unmarshal([]byte(line), rh)
-	if err != nil {
-		return nil, err
+// the line must be exactly as long as the header layout, representable in the encoding of the file,
+// name a report file with header, and hold a valid date and a zero padded record count;
+// errors are of type *HeaderError
+func (rh *RankingHeader) Parse(line string, enc Encoding) (*RankingHeader, error) {
+	if err := headerLayout.CheckLine(line, enc); err != nil {
+		return nil, &HeaderError{Err: err}
+	}
+	if lines := slice([]rune(line), 25, 8); !isDigits(lines) {
+		return nil, newHeaderError("", "Lines", "record count %q is not a zero padded number", lines)
+	}
+	if err := headerLayout.Parse(line, rh); err != nil {
+		return nil, &HeaderError{Err: err}
+	}
+	if !isHeaderFile(rh.FileName) {
+		return nil, newHeaderError("", "FileName", "unknown file name %q", rh.FileName)
 	}
+	var err error
 	rh.Date, err = time.Parse("20060102", rh.DateStr)
 	if err != nil {
-		return nil, err
+		return nil, newHeaderError("", "DateStr", "invalid date %q: expected YYYYMMDD", rh.DateStr)
 	}
 	return rh, nil
 }
 
-func (rh *RankingHeader) Validate(name string, lines int64) error {
+// Validate checks the header against the expected file name, acquirer and line count
+// and checks that its date is neither in the future nor before the reference quarter
+// an empty acquirer skips the acquirer check and a zero period skips the quarter check
+// errors are of type *HeaderError
+func (rh *RankingHeader) Validate(name string, opts port.ParseOptions, lines int64) error {
 	if rh.FileName != name {
-		return fmt.Errorf("invalid file name: expected %s, got %s", name, rh.FileName)
+		return newHeaderError(name, "FileName", "expected %s, got %s", name, rh.FileName)
 	}
 	if rh.Lines != lines {
-		return fmt.Errorf("invalid line count: expected %d, got %d", lines, rh.Lines)
+		return newHeaderError(name, "Lines", "expected %d records, got %d", lines, rh.Lines)
 	}
-	if rh.Acquirer != "47377613" {
-		return fmt.Errorf("invalid acquirer: expected 47377613, got %s", rh.Acquirer)
+	if opts.Acquirer != "" && rh.Acquirer != opts.Acquirer {
+		return newHeaderError(name, "Acquirer", "expected %s, got %s", opts.Acquirer, rh.Acquirer)
+	}
+	if today := time.Now().Format("20060102"); rh.Date.Format("20060102") > today {
+		return newHeaderError(name, "DateStr", "date %s is in the future", rh.DateStr)
+	}
+	if !opts.Period.IsZero() && rh.Date.Before(opts.Period.Start()) {
+		return newHeaderError(name, "DateStr", "date %s is before reference period %s", rh.DateStr, opts.Period)
 	}
 	return nil
 }
+
+// isHeaderFile reports whether a name is a registered report file starting with a header
+func isHeaderFile(name string) bool {
+	for _, r := range reports {
+		if r.Header && r.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isDigits reports whether a string is made of ASCII digits only
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
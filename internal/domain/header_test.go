@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lavinas/cadoc6334/internal/port"
+)
+
+func TestRankingHeaderParse(t *testing.T) {
+	ascii, err := NewEncoding("ascii", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		line  string
+		enc   Encoding
+		field string
+	}{
+		{name: "valid", line: "RANKING 202507011234567800000010", enc: DefaultEncoding},
+		{name: "short line", line: "RANKING 20250701123456780000001", enc: DefaultEncoding, field: "-"},
+		{name: "long line", line: "RANKING 2025070112345678000000100", enc: DefaultEncoding, field: "-"},
+		{name: "unknown file name", line: "UNKNOWN 202507011234567800000010", enc: DefaultEncoding, field: "FileName"},
+		{name: "non-digit record count", line: "RANKING 202507011234567800000A10", enc: DefaultEncoding, field: "Lines"},
+		{name: "padded record count", line: "RANKING 2025070112345678      10", enc: DefaultEncoding, field: "Lines"},
+		{name: "invalid date", line: "RANKING 202513311234567800000010", enc: DefaultEncoding, field: "DateStr"},
+		{name: "latin-1 in latin-1 file", line: "RANKING 20250701ÁB34567800000010", enc: DefaultEncoding},
+		{name: "latin-1 in ascii file", line: "RANKING 20250701ÁB34567800000010", enc: ascii, field: "-"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := (&RankingHeader{}).Parse(tt.line, tt.enc)
+			if tt.field == "" {
+				if err != nil {
+					t.Fatalf("Parse() error: %v", err)
+				}
+				return
+			}
+			var he *HeaderError
+			if !errors.As(err, &he) {
+				t.Fatalf("Parse() = %v, expected *HeaderError", err)
+			}
+			if tt.field != "-" && he.Field != tt.field {
+				t.Errorf("Parse() error on field %q, expected %q: %v", he.Field, tt.field, err)
+			}
+		})
+	}
+}
+
+func TestRankingHeaderValidate(t *testing.T) {
+	period := port.Period{Year: 2025, Quarter: 3}
+	opts := port.ParseOptions{Period: period, Acquirer: "12345678"}
+	header := func(date time.Time) *RankingHeader {
+		return &RankingHeader{FileName: "RANKING", DateStr: date.Format("20060102"), Date: date, Acquirer: "12345678", Lines: 10}
+	}
+	tests := []struct {
+		name   string
+		header *RankingHeader
+		file   string
+		lines  int64
+		field  string
+	}{
+		{name: "valid", header: header(period.End()), file: "RANKING", lines: 10},
+		{name: "other file", header: header(period.End()), file: "CONCCRED", lines: 10, field: "FileName"},
+		{name: "record count", header: header(period.End()), file: "RANKING", lines: 11, field: "Lines"},
+		{name: "future date", header: header(time.Now().AddDate(0, 0, 2)), file: "RANKING", lines: 10, field: "DateStr"},
+		{name: "date before the quarter", header: header(period.Start().AddDate(0, 0, -1)), file: "RANKING", lines: 10, field: "DateStr"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.header.Validate(tt.file, opts, tt.lines)
+			if tt.field == "" {
+				if err != nil {
+					t.Fatalf("Validate() error: %v", err)
+				}
+				return
+			}
+			var he *HeaderError
+			if !errors.As(err, &he) || he.Field != tt.field {
+				t.Errorf("Validate() = %v, expected a header error on %s", err, tt.field)
+			}
+		})
+	}
+}
@@ -1,26 +1,33 @@
 package domain
 
 import (
-	"bufio"
 	"fmt"
-	"os"
 
-	"github.com/ianlopshire/go-fixedwidth"
 	"github.com/lavinas/cadoc6334/internal/port"
-	"golang.org/x/text/encoding/charmap"
 )
 
 // Infresta represents the infresta data model
 type Infresta struct {
-	Year              int64  `fixed:"1,4" gorm:"column:ano"`
-	Quarter           int64  `fixed:"5,5" gorm:"column:trimestre"`
-	UF                string `fixed:"6,7" gorm:"column:uf"`
-	TotalCli          int64  `fixed:"8,15" gorm:"column:quantidade_estabelecimentos_totais"`
-	TotalCliManual    int64  `fixed:"16,23" gorm:"column:quantidade_estabelecimentos_captura_manual"`
-	TotalCliEletronic int64  `fixed:"24,31" gorm:"column:quantidade_estabelecimentos_captura_eletronica"`
-	TotalCliRemote    int64  `fixed:"32,39" gorm:"column:quantidade_estabelecimentos_captura_remota"`
+	Year              int64  `gorm:"column:ano"`
+	Quarter           int64  `gorm:"column:trimestre"`
+	UF                string `gorm:"column:uf"`
+	TotalCli          int64  `gorm:"column:quantidade_estabelecimentos_totais"`
+	TotalCliManual    int64  `gorm:"column:quantidade_estabelecimentos_captura_manual"`
+	TotalCliEletronic int64  `gorm:"column:quantidade_estabelecimentos_captura_eletronica"`
+	TotalCliRemote    int64  `gorm:"column:quantidade_estabelecimentos_captura_remota"`
 }
 
+// infrestaLayout describes the INFRESTA.TXT record
+var infrestaLayout = registerLayout("INFRESTA", &Infresta{},
+	numericAt("Year", 1, 4),
+	numericAt("Quarter", 5, 1).oneOf("1", "2", "3", "4"),
+	textAt("UF", 6, 2).oneOf(ufs...),
+	numericAt("TotalCli", 8, 8),
+	numericAt("TotalCliManual", 16, 8),
+	numericAt("TotalCliEletronic", 24, 8),
+	numericAt("TotalCliRemote", 32, 8),
+)
+
 // NewInfresta creates a new Infresta instance
 func NewInfresta() *Infresta {
 	return &Infresta{}
@@ -33,15 +40,7 @@ func (r *Infresta) GetName() string {
 
 // Format marshals the Infresta struct into a fixed-width format.
 func (r *Infresta) Format() string {
-	ret := ""
-	ret += fmt.Sprintf("%04d", r.Year)
-	ret += fmt.Sprintf("%01d", r.Quarter)
-	ret += fmt.Sprintf("%-2s", r.UF)
-	ret += fmt.Sprintf("%08d", r.TotalCli)
-	ret += fmt.Sprintf("%08d", r.TotalCliManual)
-	ret += fmt.Sprintf("%08d", r.TotalCliEletronic)
-	ret += fmt.Sprintf("%08d", r.TotalCliRemote)
-	return ret
+	return infrestaLayout.Format(r)
 }
 
 // Validate validates the Infresta header information.
@@ -67,7 +66,7 @@ func (r *Infresta) Validate() error {
 	if r.TotalCliRemote < 0 {
 		return fmt.Errorf("invalid total remote clients in header")
 	}
-	return nil
+	return infrestaLayout.Validate(r)
 }
 
 // TableName returns the table name for the Infresta struct
@@ -81,14 +80,17 @@ func (r *Infresta) GetKey() string {
 }
 
 // FindAll retrieves all Infresta records.
-func (r *Infresta) GetDB(repo port.Repository) (map[string]port.Report, error) {
+func (r *Infresta) GetDB(repo port.Repository, period port.Period) (map[string]port.Report, error) {
 	var records []*Infresta
-	err := repo.FindAll(&records, 0, 0, "")
+	err := repo.FindAll(&records, 0, 0, "", "ano = ? AND trimestre = ?", period.Year, period.Quarter)
 	if err != nil {
 		return nil, err
 	}
 	ret := make(map[string]port.Report)
 	for _, rec := range records {
+		if err := period.Check(rec.Year, rec.Quarter); err != nil {
+			return nil, fmt.Errorf("key %s: %w", rec.GetKey(), err)
+		}
 		ret[rec.GetKey()] = rec
 	}
 	return ret, nil
@@ -96,8 +98,7 @@ func (r *Infresta) GetDB(repo port.Repository) (map[string]port.Report, error) {
 
 // Parse parses a line of text into an Infresta struct
 func (r *Infresta) Parse(line string) (*Infresta, error) {
-	err := fixedwidth.Unmarshal([]byte(line), r)
-	if err != nil {
+	if err := infrestaLayout.Parse(line, r); err != nil {
 		return nil, err
 	}
 	return r, nil
@@ -109,52 +110,42 @@ func (r *Infresta) String() string {
 		r.Year, r.Quarter, r.UF, r.TotalCli, r.TotalCliManual, r.TotalCliEletronic, r.TotalCliRemote)
 }
 
-// LoadInfrestaFile loads infresta data from a file
-func (r *Infresta) LoadInfrestaFile(filename string) ([]*Infresta, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, err
+// GetFields returns the Infresta fields for field level comparison
+func (r *Infresta) GetFields() []port.Field {
+	return []port.Field{
+		intField("Year", r.Year),
+		intField("Quarter", r.Quarter),
+		textField("UF", r.UF),
+		intField("TotalCli", r.TotalCli),
+		intField("TotalCliManual", r.TotalCliManual),
+		intField("TotalCliEletronic", r.TotalCliEletronic),
+		intField("TotalCliRemote", r.TotalCliRemote),
 	}
-	defer file.Close()
+}
 
-	ret := []*Infresta{}
-	decoder := charmap.ISO8859_1.NewDecoder()
-	decodedReader := decoder.Reader(file)
-	scanner := bufio.NewScanner(decodedReader)
-	// header line
-	if !scanner.Scan() {
-		return nil, fmt.Errorf("file is empty")
-	}
-	headerLine := scanner.Text()
-	header := &RankingHeader{}
-	_, err = header.Parse(headerLine)
+// LoadInfrestaFile loads infresta data from a file
+func (r *Infresta) LoadInfrestaFile(filename string, opts port.ParseOptions) ([]*Infresta, error) {
+	reader, err := openFile(filename, "INFRESTA", opts)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing header: %w", err)
+		return nil, err
 	}
 	// data lines
-	var count int64 = 0
-	for scanner.Scan() {
-		line := scanner.Text()
-		inf := &Infresta{}
-		parsedInf, err := inf.Parse(line)
-		if err != nil {
-			return nil, err
+	ret := []*Infresta{}
+	for reader.Next() {
+		parsedInf := &Infresta{}
+		if reader.Parse(parsedInf) && reader.CheckPeriod(parsedInf.Year, parsedInf.Quarter) {
+			ret = append(ret, parsedInf)
 		}
-		ret = append(ret, parsedInf)
-		count++
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
 	}
-	if err := header.Validate("INFRESTA", count); err != nil {
+	if err := reader.Close(); err != nil {
 		return nil, err
 	}
 	return ret, nil
 }
 
 // GetParsedFile retrieves and maps Infresta records from a file.
-func (r *Infresta) GetParsedFile(filename string) (map[string]port.Report, error) {
-	fileInfresta, err := r.LoadInfrestaFile(filename)
+func (r *Infresta) GetParsedFile(filename string, opts port.ParseOptions) (map[string]port.Report, error) {
+	fileInfresta, err := r.LoadInfrestaFile(filename, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -1,26 +1,33 @@
 package domain
 
 import (
-	"bufio"
 	"fmt"
-	"os"
 
-	"github.com/ianlopshire/go-fixedwidth"
 	"github.com/lavinas/cadoc6334/internal/port"
-	"golang.org/x/text/encoding/charmap"
 )
 
 // Infrterm represents the infrterm data model
 type Infrterm struct {
-	Year               int64  `fixed:"1,4" gorm:"column:ano"`
-	Quarter            int64  `fixed:"5,5" gorm:"column:trimestre"`
-	UF                 string `fixed:"6,7" gorm:"column:uf"`
-	TotalPOSCount      int64  `fixed:"8,15" gorm:"column:quantidade_pos_totais"`
-	SharedPOSCount     int64  `fixed:"16,23" gorm:"column:quantidade_pos_compartilhados"`
-	ChipReaderPOSCount int64  `fixed:"24,31" gorm:"column:quantidade_pos_leitora_chip"`
-	PDVCount           int64  `fixed:"32,39" gorm:"column:quantidade_pdv"`
+	Year               int64  `gorm:"column:ano"`
+	Quarter            int64  `gorm:"column:trimestre"`
+	UF                 string `gorm:"column:uf"`
+	TotalPOSCount      int64  `gorm:"column:quantidade_pos_totais"`
+	SharedPOSCount     int64  `gorm:"column:quantidade_pos_compartilhados"`
+	ChipReaderPOSCount int64  `gorm:"column:quantidade_pos_leitora_chip"`
+	PDVCount           int64  `gorm:"column:quantidade_pdv"`
 }
 
+// infrtermLayout describes the INFRTERM.TXT record
+var infrtermLayout = registerLayout("INFRTERM", &Infrterm{},
+	numericAt("Year", 1, 4),
+	numericAt("Quarter", 5, 1).oneOf("1", "2", "3", "4"),
+	textAt("UF", 6, 2).oneOf(ufs...),
+	numericAt("TotalPOSCount", 8, 8),
+	numericAt("SharedPOSCount", 16, 8),
+	numericAt("ChipReaderPOSCount", 24, 8),
+	numericAt("PDVCount", 32, 8),
+)
+
 // NewInfrterm creates a new Infrterm instance
 func NewInfrterm() *Infrterm {
 	return &Infrterm{}
@@ -33,15 +40,7 @@ func (r *Infrterm) GetName() string {
 
 // Format marshals the Infrterm struct into a fixed-width format.
 func (r *Infrterm) Format() string {
-	ret := ""
-	ret += fmt.Sprintf("%04d", r.Year)
-	ret += fmt.Sprintf("%01d", r.Quarter)
-	ret += fmt.Sprintf("%-2s", r.UF)
-	ret += fmt.Sprintf("%08d", r.TotalPOSCount)
-	ret += fmt.Sprintf("%08d", r.SharedPOSCount)
-	ret += fmt.Sprintf("%08d", r.ChipReaderPOSCount)
-	ret += fmt.Sprintf("%08d", r.PDVCount)
-	return ret
+	return infrtermLayout.Format(r)
 }
 
 // Validate validates the Infrterm header information.
@@ -67,7 +66,7 @@ func (r *Infrterm) Validate() error {
 	if r.PDVCount < 0 {
 		return fmt.Errorf("invalid PDV count in header")
 	}
-	return nil
+	return infrtermLayout.Validate(r)
 }
 
 // TableName returns the table name for the Infrterm struct
@@ -81,14 +80,17 @@ func (r *Infrterm) GetKey() string {
 }
 
 // FindAll retrieves all Infrterm records.
-func (r *Infrterm) GetDB(repo port.Repository) (map[string]port.Report, error) {
+func (r *Infrterm) GetDB(repo port.Repository, period port.Period) (map[string]port.Report, error) {
 	var records []*Infrterm
-	err := repo.FindAll(&records, 0, 0, "")
+	err := repo.FindAll(&records, 0, 0, "", "ano = ? AND trimestre = ?", period.Year, period.Quarter)
 	if err != nil {
 		return nil, err
 	}
 	ret := make(map[string]port.Report)
 	for _, rec := range records {
+		if err := period.Check(rec.Year, rec.Quarter); err != nil {
+			return nil, fmt.Errorf("key %s: %w", rec.GetKey(), err)
+		}
 		ret[rec.GetKey()] = rec
 	}
 	return ret, nil
@@ -96,11 +98,7 @@ func (r *Infrterm) GetDB(repo port.Repository) (map[string]port.Report, error) {
 
 // Parse parses a fixed-width string into an Infrterm struct
 func (r *Infrterm) Parse(line string) error {
-	err := fixedwidth.Unmarshal([]byte(line), r)
-	if err != nil {
-		return err
-	}
-	return nil
+	return infrtermLayout.Parse(line, r)
 }
 
 // String returns a string representation of the Infrterm struct
@@ -109,52 +107,42 @@ func (r *Infrterm) String() string {
 		r.Year, r.Quarter, r.UF, r.TotalPOSCount, r.SharedPOSCount, r.ChipReaderPOSCount, r.PDVCount)
 }
 
-// LoadInfrtermFile loads infrterm data from a fixed-width file
-func (i *Infrterm) LoadInfrtermFile(filename string) ([]*Infrterm, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, err
+// GetFields returns the Infrterm fields for field level comparison
+func (r *Infrterm) GetFields() []port.Field {
+	return []port.Field{
+		intField("Year", r.Year),
+		intField("Quarter", r.Quarter),
+		textField("UF", r.UF),
+		intField("TotalPOSCount", r.TotalPOSCount),
+		intField("SharedPOSCount", r.SharedPOSCount),
+		intField("ChipReaderPOSCount", r.ChipReaderPOSCount),
+		intField("PDVCount", r.PDVCount),
 	}
-	defer file.Close()
+}
 
-	var r []*Infrterm
-	decoder := charmap.ISO8859_1.NewDecoder()
-	decodedReader := decoder.Reader(file)
-	scanner := bufio.NewScanner(decodedReader)
-	// header line
-	if !scanner.Scan() {
-		return nil, fmt.Errorf("file is empty")
-	}
-	headerLine := scanner.Text()
-	header := &RankingHeader{}
-	_, err = header.Parse(headerLine)
+// LoadInfrtermFile loads infrterm data from a fixed-width file
+func (i *Infrterm) LoadInfrtermFile(filename string, opts port.ParseOptions) ([]*Infrterm, error) {
+	reader, err := openFile(filename, "INFRTERM", opts)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing header: %w", err)
+		return nil, err
 	}
 	// data lines
-	var count int64 = 0
-	for scanner.Scan() {
-		line := scanner.Text()
+	var r []*Infrterm
+	for reader.Next() {
 		inf := &Infrterm{}
-		err := inf.Parse(line)
-		if err != nil {
-			return nil, err
+		if reader.Parse(inf) && reader.CheckPeriod(inf.Year, inf.Quarter) {
+			r = append(r, inf)
 		}
-		r = append(r, inf)
-		count++
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
 	}
-	if err := header.Validate("INFRTERM", count); err != nil {
+	if err := reader.Close(); err != nil {
 		return nil, err
 	}
 	return r, nil
 }
 
 // GetParsedFile retrieves and maps Infrterm records from a file.
-func (r *Infrterm) GetParsedFile(filename string) (map[string]port.Report, error) {
-	fileInfrterm, err := r.LoadInfrtermFile(filename)
+func (r *Infrterm) GetParsedFile(filename string, opts port.ParseOptions) (map[string]port.Report, error) {
+	fileInfrterm, err := r.LoadInfrtermFile(filename, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lavinas/cadoc6334/internal/port"
+)
+
+var cnpjBaseRegex = regexp.MustCompile(`^\d{8}$`)
+
+// Institution represents the acquirer (credenciadora) filing the CADOC reports
+// Filters restrict the database rows to the institution (column: value)
+// OutputDir is the output subdirectory on multi-institution runs; defaults to the CNPJ base
+type Institution struct {
+	CNPJBase  string               `yaml:"cnpj_base"`
+	Name      string               `yaml:"name"`
+	Contacts  []InstitutionContact `yaml:"contacts"`
+	Filters   map[string]string    `yaml:"filters"`
+	OutputDir string               `yaml:"output_dir"`
+}
+
+// InstitutionContact represents a contact person of the institution
+type InstitutionContact struct {
+	Type     string `yaml:"type"`
+	Name     string `yaml:"name"`
+	Position string `yaml:"position"`
+	Phone    string `yaml:"phone"`
+	Email    string `yaml:"email"`
+}
+
+// NewInstitution creates a new Institution instance
+func NewInstitution(cnpjBase string, name string, contacts []InstitutionContact) *Institution {
+	return &Institution{
+		CNPJBase: cnpjBase,
+		Name:     name,
+		Contacts: contacts,
+	}
+}
+
+// Validate validates the Institution information.
+func (i *Institution) Validate() error {
+	if !cnpjBaseRegex.MatchString(i.CNPJBase) {
+		return fmt.Errorf("invalid institution cnpj base %q: expected 8 digits", i.CNPJBase)
+	}
+	if i.Name == "" {
+		return fmt.Errorf("invalid institution name for cnpj base %s", i.CNPJBase)
+	}
+	for _, c := range i.Contacts {
+		if c.Type == "" || c.Email == "" {
+			return fmt.Errorf("invalid contact %q for institution %s: type and email are required", c.Name, i.CNPJBase)
+		}
+	}
+	return nil
+}
+
+// String returns a string representation of the Institution
+func (i *Institution) String() string {
+	return strings.TrimSpace(fmt.Sprintf("%s %s", i.CNPJBase, i.Name))
+}
+
+// GetContacts returns the configured contacts as CONTATOS records of the period
+func (i *Institution) GetContacts(period port.Period) map[string]port.Report {
+	ret := make(map[string]port.Report)
+	for _, c := range i.Contacts {
+		contact := &Contact{
+			Year:        period.Year,
+			Quarter:     period.Quarter,
+			ContactType: c.Type,
+			Name:        c.Name,
+			Position:    c.Position,
+			Phone:       c.Phone,
+			Email:       c.Email,
+		}
+		ret[contact.GetKey()] = contact
+	}
+	return ret
+}
+
+// GetOutputDir returns the output subdirectory of the institution
+func (i *Institution) GetOutputDir() string {
+	if i.OutputDir != "" {
+		return i.OutputDir
+	}
+	return i.CNPJBase
+}
+
+// GetRepository returns the repository scoped to the institution filters
+func (i *Institution) GetRepository(repo port.Repository) port.Repository {
+	if len(i.Filters) == 0 {
+		return repo
+	}
+	conditions := make(map[string]interface{}, len(i.Filters))
+	for column, value := range i.Filters {
+		conditions[column] = value
+	}
+	return repo.Where(conditions)
+}
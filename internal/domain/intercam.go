@@ -1,33 +1,44 @@
 package domain
 
 import (
-	"bufio"
 	"fmt"
-	"os"
 
-	"github.com/ianlopshire/go-fixedwidth"
 	"github.com/lavinas/cadoc6334/internal/port"
-	"golang.org/x/text/encoding/charmap"
+	"github.com/shopspring/decimal"
 )
 
 // Intercam represents the intercam data model
 type Intercam struct {
-	Year         int64   `fixed:"1,4" gorm:"column:ano"`
-	Quarter      int64   `fixed:"5,5" gorm:"column:trimestre"`
-	Product      int64   `fixed:"6,7" gorm:"column:produto"`
-	CardType     string  `fixed:"8,8" gorm:"column:modalidade_cartao"`
-	Function     string  `fixed:"9,9" gorm:"column:funcao"`
-	Brand        int64   `fixed:"10,11" gorm:"column:bandeira"`
-	Capture      int64   `fixed:"12,12" gorm:"column:forma_captura"`
-	Installments int64   `fixed:"13,14" gorm:"column:numero_parcelas"`
-	Segment      int64   `fixed:"15,17" gorm:"column:codigo_segmento"`
-	Fee          float64 `gorm:"column:tarifa_intercambio"`
-	FeeInt       int64   `fixed:"18,21"`
-	Value        float64 `gorm:"column:valor_transacoes"`
-	ValueInt     int64   `fixed:"22,36"`
-	Qtty         int64   `fixed:"37,48" gorm:"column:quantidade_transacoes"`
+	Year         int64           `gorm:"column:ano"`
+	Quarter      int64           `gorm:"column:trimestre"`
+	Product      int64           `gorm:"column:produto"`
+	CardType     string          `gorm:"column:modalidade_cartao"`
+	Function     string          `gorm:"column:funcao"`
+	Brand        int64           `gorm:"column:bandeira"`
+	Capture      int64           `gorm:"column:forma_captura"`
+	Installments int64           `gorm:"column:numero_parcelas"`
+	Segment      int64           `gorm:"column:codigo_segmento"`
+	Fee          decimal.Decimal `gorm:"column:tarifa_intercambio"`
+	Value        decimal.Decimal `gorm:"column:valor_transacoes"`
+	Qtty         int64           `gorm:"column:quantidade_transacoes"`
 }
 
+// intercamLayout describes the INTERCAM.TXT record
+var intercamLayout = registerLayout("INTERCAM", &Intercam{},
+	numericAt("Year", 1, 4),
+	numericAt("Quarter", 5, 1).oneOf("1", "2", "3", "4"),
+	numericAt("Product", 6, 2),
+	textAt("CardType", 8, 1),
+	textAt("Function", 9, 1),
+	numericAt("Brand", 10, 2),
+	numericAt("Capture", 12, 1),
+	numericAt("Installments", 13, 2),
+	numericAt("Segment", 15, 3),
+	decimalAt("Fee", 18, 4, 2),
+	decimalAt("Value", 22, 15, 2),
+	numericAt("Qtty", 37, 12),
+)
+
 // NewIntercam creates a new Intercam instance
 func NewIntercam() *Intercam {
 	return &Intercam{}
@@ -40,24 +51,7 @@ func (i *Intercam) GetName() string {
 
 // Format marshals the Intercam struct into a fixed-width format.
 func (i *Intercam) Format() string {
-	ret := ""
-	ret += fmt.Sprintf("%04d", i.Year)
-	ret += fmt.Sprintf("%01d", i.Quarter)
-	ret += fmt.Sprintf("%02d", i.Product)
-	ret += fmt.Sprintf("%01s", i.CardType)
-	ret += fmt.Sprintf("%01s", i.Function)
-	ret += fmt.Sprintf("%02d", i.Brand)
-	ret += fmt.Sprintf("%01d", i.Capture)
-	ret += fmt.Sprintf("%02d", i.Installments)
-	ret += fmt.Sprintf("%03d", i.Segment)
-	// Convert Fee to int representation
-	i.FeeInt = int64(i.Fee*100 + 0.5)
-	ret += fmt.Sprintf("%04d", i.FeeInt)
-	// Convert Value to int representation
-	i.ValueInt = int64(i.Value*100 + 0.5)
-	ret += fmt.Sprintf("%015d", i.ValueInt)
-	ret += fmt.Sprintf("%012d", i.Qtty)
-	return ret
+	return intercamLayout.Format(i)
 }
 
 // Validate validates the Intercam header information.
@@ -89,16 +83,16 @@ func (i *Intercam) Validate() error {
 	if i.Segment <= 0 {
 		return fmt.Errorf("invalid segment in header")
 	}
-	if i.Fee < 0 {
+	if i.Fee.IsNegative() {
 		return fmt.Errorf("invalid fee in header")
 	}
-	if i.Value < 0 {
+	if i.Value.IsNegative() {
 		return fmt.Errorf("invalid value in header")
 	}
 	if i.Qtty < 0 {
 		return fmt.Errorf("invalid quantity in header")
 	}
-	return nil
+	return intercamLayout.Validate(i)
 }
 
 // TableName returns the table name for the Intercam struct
@@ -112,14 +106,17 @@ func (i *Intercam) GetKey() string {
 }
 
 // FindAll retrieves all Intercam records.
-func (i *Intercam) GetDB(repo port.Repository) (map[string]port.Report, error) {
+func (i *Intercam) GetDB(repo port.Repository, period port.Period) (map[string]port.Report, error) {
 	var records []*Intercam
-	err := repo.FindAll(&records, 0, 0, "")
+	err := repo.FindAll(&records, 0, 0, "", "ano = ? AND trimestre = ?", period.Year, period.Quarter)
 	if err != nil {
 		return nil, err
 	}
 	ret := make(map[string]port.Report)
 	for _, r := range records {
+		if err := period.Check(r.Year, r.Quarter); err != nil {
+			return nil, fmt.Errorf("key %s: %w", r.GetKey(), err)
+		}
 		ret[r.GetKey()] = r
 	}
 	return ret, nil
@@ -127,68 +124,59 @@ func (i *Intercam) GetDB(repo port.Repository) (map[string]port.Report, error) {
 
 // Parse parses a line of text into an Intercam struct
 func (i *Intercam) Parse(line string) (*Intercam, error) {
-	err := fixedwidth.Unmarshal([]byte(line), i)
-	if err != nil {
+	if err := intercamLayout.Parse(line, i); err != nil {
 		return nil, err
 	}
-	// Convert ValueInt and FeeInt back to float64
-	i.Value = float64(float64(i.ValueInt) / float64(100))
-	i.Fee = float64(float64(i.FeeInt) / float64(100))
 	return i, nil
 }
 
 // String returns a string representation of the Intercam struct
 func (i *Intercam) String() string {
-	return fmt.Sprintf("Year: %d, Quarter: %d, Product: %d, CardType: %s, Function: %s, Brand: %d, Capture: %d, Installments: %d, Segment: %d, Fee: %.2f, Value: %.2f, Qtty: %d",
-		i.Year, i.Quarter, i.Product, i.CardType, i.Function, i.Brand, i.Capture, i.Installments, i.Segment, i.Fee, i.Value, i.Qtty)
+	return fmt.Sprintf("Year: %d, Quarter: %d, Product: %d, CardType: %s, Function: %s, Brand: %d, Capture: %d, Installments: %d, Segment: %d, Fee: %s, Value: %s, Qtty: %d",
+		i.Year, i.Quarter, i.Product, i.CardType, i.Function, i.Brand, i.Capture, i.Installments, i.Segment, i.Fee.StringFixed(2), i.Value.StringFixed(2), i.Qtty)
 }
 
-// ParseIntercamFile parses the intercam file and returns a slice of Intercam structs
-func (i *Intercam) ParseIntercamFile(filename string) ([]*Intercam, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, err
+// GetFields returns the Intercam fields for field level comparison
+func (i *Intercam) GetFields() []port.Field {
+	return []port.Field{
+		intField("Year", i.Year),
+		intField("Quarter", i.Quarter),
+		intField("Product", i.Product),
+		textField("CardType", i.CardType),
+		textField("Function", i.Function),
+		intField("Brand", i.Brand),
+		intField("Capture", i.Capture),
+		intField("Installments", i.Installments),
+		intField("Segment", i.Segment),
+		rateField("Fee", i.Fee),
+		amountField("Value", i.Value),
+		intField("Qtty", i.Qtty),
 	}
-	defer file.Close()
+}
 
-	var intercams []*Intercam
-	decoder := charmap.ISO8859_1.NewDecoder()
-	decodedReader := decoder.Reader(file)
-	scanner := bufio.NewScanner(decodedReader)
-	// read header
-	if !scanner.Scan() {
-		return nil, fmt.Errorf("file is empty")
-	}
-	headerLine := scanner.Text()
-	header := &RankingHeader{}
-	_, err = header.Parse(headerLine)
+// ParseIntercamFile parses the intercam file and returns a slice of Intercam structs
+func (i *Intercam) ParseIntercamFile(filename string, opts port.ParseOptions) ([]*Intercam, error) {
+	reader, err := openFile(filename, "INTERCAM", opts)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing header: %w", err)
+		return nil, err
 	}
 	// read records
-	var count int64 = 0
-	for scanner.Scan() {
-		line := scanner.Text()
+	var intercams []*Intercam
+	for reader.Next() {
 		intercam := &Intercam{}
-		_, err := intercam.Parse(line)
-		if err != nil {
-			return nil, err
+		if reader.Parse(intercam) && reader.CheckPeriod(intercam.Year, intercam.Quarter) {
+			intercams = append(intercams, intercam)
 		}
-		intercams = append(intercams, intercam)
-		count++
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
 	}
-	if err := header.Validate("INTERCAM", count); err != nil {
+	if err := reader.Close(); err != nil {
 		return nil, err
 	}
 	return intercams, nil
 }
 
 // GetParsedFile retrieves and maps Intercam records from a file.
-func (i *Intercam) GetParsedFile(filename string) (map[string]port.Report, error) {
-	fileIntercam, err := i.ParseIntercamFile(filename)
+func (i *Intercam) GetParsedFile(filename string, opts port.ParseOptions) (map[string]port.Report, error) {
+	fileIntercam, err := i.ParseIntercamFile(filename, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,532 @@
+package domain
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lavinas/cadoc6334/internal/port"
+	"github.com/shopspring/decimal"
+)
+
+// LayoutType represents how a layout field is written on the file
+type LayoutType string
+
+const (
+	// LayoutNumeric is an integer, right aligned and zero padded
+	LayoutNumeric LayoutType = "numeric"
+	// LayoutText is a text, left aligned and space padded
+	LayoutText LayoutType = "text"
+	// LayoutDecimal is a decimal written as an integer with implied decimal places, right aligned and zero padded
+	LayoutDecimal LayoutType = "decimal"
+	// LayoutFiller is a reserved or discontinued area, written as spaces and ignored on parsing
+	LayoutFiller LayoutType = "filler"
+)
+
+// BuiltinVersion is the version of the layouts defined in the code, used when no loaded version is in force
+const BuiltinVersion = "builtin"
+
+var (
+	decimalType = reflect.TypeOf(decimal.Decimal{})
+	goTypes     = map[LayoutType]reflect.Type{
+		LayoutNumeric: reflect.TypeOf(int64(0)),
+		LayoutText:    reflect.TypeOf(""),
+		LayoutDecimal: decimalType,
+	}
+)
+
+// LayoutField describes one field of a fixed-width record
+// Name is the record struct field; Start is the 1-based first position
+// Signed fields may be negative, with the minus sign on the first position ("-00000001234")
+// Domain lists the accepted values as written on the file; empty accepts any value
+type LayoutField struct {
+	Name     string     `yaml:"name"`
+	Start    int        `yaml:"start"`
+	Width    int        `yaml:"width"`
+	Type     LayoutType `yaml:"type"`
+	Decimals int32      `yaml:"decimals"`
+	Signed   bool       `yaml:"signed"`
+	Domain   []string   `yaml:"domain"`
+}
+
+// Layout describes a fixed-width record of a CADOC file and drives its Format, Parse and Validate
+// a layout version is in force from EffectiveFrom to EffectiveTo; a zero period leaves that side open
+type Layout struct {
+	Name          string
+	Version       string
+	EffectiveFrom port.Period
+	EffectiveTo   port.Period
+	Fields        []LayoutField
+	record        reflect.Type
+}
+
+// LayoutSpec describes a layout version loaded from a YAML layout file
+// the periods are in the YYYYQn format; fields follow the LayoutField names
+type LayoutSpec struct {
+	Report        string        `yaml:"report"`
+	Version       string        `yaml:"version"`
+	EffectiveFrom string        `yaml:"effective_from"`
+	EffectiveTo   string        `yaml:"effective_to"`
+	Fields        []LayoutField `yaml:"fields"`
+}
+
+// ufs lists the Brazilian federative units
+var ufs = []string{"AC", "AL", "AP", "AM", "BA", "CE", "DF", "ES", "GO", "MA", "MT", "MS", "MG", "PA", "PB", "PR", "PE", "PI",
+	"RJ", "RN", "RS", "RO", "RR", "SC", "SP", "SE", "TO"}
+
+// layouts holds the registered layout versions by name
+var layouts = make(map[string][]*Layout)
+
+// numericAt describes an integer field
+func numericAt(name string, start int, width int) LayoutField {
+	return LayoutField{Name: name, Start: start, Width: width, Type: LayoutNumeric}
+}
+
+// textAt describes a text field
+func textAt(name string, start int, width int) LayoutField {
+	return LayoutField{Name: name, Start: start, Width: width, Type: LayoutText}
+}
+
+// decimalAt describes a decimal field with implied decimal places
+func decimalAt(name string, start int, width int, decimals int32) LayoutField {
+	return LayoutField{Name: name, Start: start, Width: width, Type: LayoutDecimal, Decimals: decimals}
+}
+
+// signed returns a copy of the field that accepts negative values
+func (f LayoutField) signed() LayoutField {
+	f.Signed = true
+	return f
+}
+
+// oneOf returns a copy of the field restricted to the given values
+func (f LayoutField) oneOf(values ...string) LayoutField {
+	f.Domain = values
+	return f
+}
+
+// End returns the last position of the field
+func (f LayoutField) End() int {
+	return f.Start + f.Width - 1
+}
+
+// String returns a one line description of the field
+func (f LayoutField) String() string {
+	ret := fmt.Sprintf("%-28s %4d-%-4d %4d %-8s", f.Name, f.Start, f.End(), f.Width, f.Type)
+	if f.Type == LayoutDecimal {
+		ret += fmt.Sprintf(" decimals: %d", f.Decimals)
+	}
+	if f.Signed {
+		ret += " signed"
+	}
+	if len(f.Domain) > 0 {
+		ret += fmt.Sprintf(" domain: %s", strings.Join(f.Domain, ","))
+	}
+	return strings.TrimRight(ret, " ")
+}
+
+// registerLayout registers the builtin layout of a record type
+// it panics when the layout does not match the record or has gaps or overlaps, as a programming error
+func registerLayout(name string, record interface{}, fields ...LayoutField) *Layout {
+	l := &Layout{Name: name, Version: BuiltinVersion, Fields: fields, record: reflect.TypeOf(record).Elem()}
+	if err := l.Check(); err != nil {
+		panic(fmt.Sprintf("invalid layout %s: %v", name, err))
+	}
+	layouts[name] = append(layouts[name], l)
+	return l
+}
+
+// RegisterLayout registers a layout version of a file with a builtin layout
+func RegisterLayout(spec LayoutSpec) (*Layout, error) {
+	versions, ok := layouts[spec.Report]
+	if !ok {
+		return nil, fmt.Errorf("unknown layout %s", spec.Report)
+	}
+	if spec.Version == "" {
+		return nil, fmt.Errorf("layout %s: version is required", spec.Report)
+	}
+	l := &Layout{Name: spec.Report, Version: spec.Version, Fields: spec.Fields, record: versions[0].record}
+	var err error
+	if spec.EffectiveFrom != "" {
+		if l.EffectiveFrom, err = port.ParsePeriod(spec.EffectiveFrom); err != nil {
+			return nil, fmt.Errorf("layout %s version %s: effective_from: %w", spec.Report, spec.Version, err)
+		}
+	}
+	if spec.EffectiveTo != "" {
+		if l.EffectiveTo, err = port.ParsePeriod(spec.EffectiveTo); err != nil {
+			return nil, fmt.Errorf("layout %s version %s: effective_to: %w", spec.Report, spec.Version, err)
+		}
+	}
+	if !l.EffectiveFrom.IsZero() && !l.EffectiveTo.IsZero() && l.EffectiveTo.Compare(l.EffectiveFrom) < 0 {
+		return nil, fmt.Errorf("layout %s version %s: effective_to before effective_from", spec.Report, spec.Version)
+	}
+	if err := l.Check(); err != nil {
+		return nil, fmt.Errorf("layout %s version %s: %w", spec.Report, spec.Version, err)
+	}
+	for _, v := range versions {
+		if v.Version == l.Version {
+			return nil, fmt.Errorf("layout %s version %s: already registered", spec.Report, spec.Version)
+		}
+	}
+	layouts[spec.Report] = append(versions, l)
+	return l, nil
+}
+
+// GetLayout returns a layout version of a file
+func GetLayout(name string, version string) (*Layout, error) {
+	for _, l := range layouts[name] {
+		if l.Version == version {
+			return l, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown layout %s version %s", name, version)
+}
+
+// LayoutFor returns the layout version of a file in force for a reference period
+// the builtin layout applies unless a loaded version covers the period; among those, the latest start wins
+// a zero period selects the version in force today, open ended
+func LayoutFor(name string, period port.Period) (*Layout, error) {
+	candidates := layoutsInForce(name, period)
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no layout %s in force for period %s", name, period)
+	}
+	return candidates[0], nil
+}
+
+// SelectLayout returns the layout version to parse a file record with
+// an explicit version in the options wins; otherwise the versions in force for the period are tried
+// before the others, and the first whose record length matches the line is used
+func SelectLayout(name string, opts port.ParseOptions, line string) (*Layout, error) {
+	if opts.LayoutVersion != "" {
+		return GetLayout(name, opts.LayoutVersion)
+	}
+	length := len([]rune(line))
+	candidates := append(layoutsInForce(name, opts.Period), layouts[name]...)
+	for _, l := range candidates {
+		if l.Length() == length {
+			return l, nil
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("unknown layout %s", name)
+	}
+	// no version matches the record length: parse with the version in force and let the fields report
+	return candidates[0], nil
+}
+
+// layoutsInForce returns the layout versions of a file in force for a period, preferred first
+func layoutsInForce(name string, period port.Period) []*Layout {
+	var ret []*Layout
+	for _, l := range layouts[name] {
+		if l.InForce(period) {
+			ret = append(ret, l)
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		bi, bj := ret[i].Version == BuiltinVersion, ret[j].Version == BuiltinVersion
+		if bi != bj {
+			return bj
+		}
+		return ret[i].EffectiveFrom.Compare(ret[j].EffectiveFrom) > 0
+	})
+	return ret
+}
+
+// GetLayouts returns the registered layout versions ordered by name and version
+func GetLayouts() []*Layout {
+	var ret []*Layout
+	for _, versions := range layouts {
+		ret = append(ret, versions...)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Name != ret[j].Name {
+			return ret[i].Name < ret[j].Name
+		}
+		return ret[i].Version < ret[j].Version
+	})
+	return ret
+}
+
+// InForce reports whether the layout version is in force for a period
+// a zero period means today: only open ended versions are in force
+func (l *Layout) InForce(period port.Period) bool {
+	if period.IsZero() {
+		return l.EffectiveTo.IsZero()
+	}
+	if !l.EffectiveFrom.IsZero() && period.Compare(l.EffectiveFrom) < 0 {
+		return false
+	}
+	return l.EffectiveTo.IsZero() || period.Compare(l.EffectiveTo) <= 0
+}
+
+// Length returns the record length of the layout
+func (l *Layout) Length() int {
+	if len(l.Fields) == 0 {
+		return 0
+	}
+	return l.Fields[len(l.Fields)-1].End()
+}
+
+// String returns a multi line description of the layout
+func (l *Layout) String() string {
+	ret := fmt.Sprintf("%s version %s: %d positions", l.Name, l.Version, l.Length())
+	if !l.EffectiveFrom.IsZero() || !l.EffectiveTo.IsZero() {
+		ret += fmt.Sprintf(" in force %s to %s", periodOrOpen(l.EffectiveFrom), periodOrOpen(l.EffectiveTo))
+	}
+	for _, f := range l.Fields {
+		ret += "\n  " + f.String()
+	}
+	return ret
+}
+
+// periodOrOpen returns the period in the YYYYQn format, or "open" for a zero period
+func periodOrOpen(p port.Period) string {
+	if p.IsZero() {
+		return "open"
+	}
+	return p.String()
+}
+
+// Check checks that the fields are contiguous from the first position and match the record struct
+func (l *Layout) Check() error {
+	next := 1
+	for _, f := range l.Fields {
+		if f.Width <= 0 {
+			return fmt.Errorf("field %s: invalid width %d", f.Name, f.Width)
+		}
+		if f.Start != next {
+			return fmt.Errorf("field %s: starts at %d, expected %d", f.Name, f.Start, next)
+		}
+		next = f.End() + 1
+		if f.Type == LayoutFiller {
+			continue
+		}
+		sf, ok := l.record.FieldByName(f.Name)
+		if !ok {
+			return fmt.Errorf("field %s: not found on %s", f.Name, l.record.Name())
+		}
+		want, ok := goTypes[f.Type]
+		if !ok {
+			return fmt.Errorf("field %s: unknown type %q", f.Name, f.Type)
+		}
+		if sf.Type != want {
+			return fmt.Errorf("field %s: %s type requires %s, got %s", f.Name, f.Type, want, sf.Type)
+		}
+		if f.Type != LayoutDecimal && f.Decimals != 0 {
+			return fmt.Errorf("field %s: implied decimals on %s field", f.Name, f.Type)
+		}
+	}
+	return nil
+}
+
+// Format writes a record as a fixed-width line
+// values wider than the field are written whole; Validate reports them and FormatLine rejects them
+func (l *Layout) Format(record interface{}) string {
+	v := reflect.ValueOf(record).Elem()
+	var sb strings.Builder
+	for _, f := range l.Fields {
+		if f.Type == LayoutFiller {
+			sb.WriteString(strings.Repeat(" ", f.Width))
+			continue
+		}
+		sb.WriteString(f.format(v.FieldByName(f.Name)))
+	}
+	return sb.String()
+}
+
+// format writes the value of a field
+func (f LayoutField) format(v reflect.Value) string {
+	switch f.Type {
+	case LayoutNumeric:
+		return fmt.Sprintf("%0*d", f.Width, v.Int())
+	case LayoutDecimal:
+		return fmt.Sprintf("%0*d", f.Width, toImplied(v.Interface().(decimal.Decimal), f.Decimals))
+	}
+	return fmt.Sprintf("%-*s", f.Width, v.String())
+}
+
+// Parse reads a fixed-width line into a record
+// positions are counted in characters; missing positions at the end of the line are read as empty
+func (l *Layout) Parse(line string, record interface{}) error {
+	v := reflect.ValueOf(record).Elem()
+	runes := []rune(line)
+	for _, f := range l.Fields {
+		if f.Type == LayoutFiller {
+			continue
+		}
+		if err := f.parse(slice(runes, f.Start, f.Width), v.FieldByName(f.Name)); err != nil {
+			return &FieldError{Field: f.Name, Start: f.Start, End: f.End(), Err: err}
+		}
+	}
+	return nil
+}
+
+// parse reads the text of a field into its value
+func (f LayoutField) parse(s string, v reflect.Value) error {
+	s = strings.TrimSpace(s)
+	if f.Type == LayoutText {
+		v.SetString(s)
+		return nil
+	}
+	var n int64
+	if s != "" {
+		var err error
+		if n, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+	}
+	if n < 0 && !f.Signed {
+		return fmt.Errorf("negative value %q on unsigned field", s)
+	}
+	if f.Type == LayoutDecimal {
+		v.Set(reflect.ValueOf(fromImplied(n, f.Decimals)))
+		return nil
+	}
+	v.SetInt(n)
+	return nil
+}
+
+// slice returns the characters of a field, or what exists of them on a short line
+func slice(runes []rune, start int, width int) string {
+	if start > len(runes) {
+		return ""
+	}
+	end := start - 1 + width
+	if end > len(runes) {
+		end = len(runes)
+	}
+	return string(runes[start-1 : end])
+}
+
+// Validate checks that every field of a record fits its width, sign and domain
+func (l *Layout) Validate(record interface{}) error {
+	v := reflect.ValueOf(record).Elem()
+	for _, f := range l.Fields {
+		if f.Type == LayoutFiller {
+			continue
+		}
+		if err := f.validate(v.FieldByName(f.Name)); err != nil {
+			return fmt.Errorf("invalid %s: %w", f.Name, err)
+		}
+	}
+	return nil
+}
+
+// validate checks the value of a field
+func (f LayoutField) validate(v reflect.Value) error {
+	s := f.format(v)
+	if n := len([]rune(s)); n > f.Width {
+		return fmt.Errorf("value %q does not fit %d positions", strings.TrimSpace(s), f.Width)
+	}
+	if f.Type != LayoutText && strings.HasPrefix(s, "-") && !f.Signed {
+		return fmt.Errorf("negative value %s on unsigned field", s)
+	}
+	if len(f.Domain) > 0 {
+		s = strings.TrimSpace(s)
+		for _, d := range f.Domain {
+			if s == d {
+				return nil
+			}
+		}
+		return fmt.Errorf("value %q not in %s", s, strings.Join(f.Domain, ","))
+	}
+	return nil
+}
+
+// SelfCheck proves that Format and Parse are inverses on a record filling every position of the layout
+// it formats a sample record, parses the line back and checks that both the line and the values survive
+func (l *Layout) SelfCheck() error {
+	sample := reflect.New(l.record)
+	for i, f := range l.Fields {
+		if f.Type != LayoutFiller {
+			f.setSample(sample.Elem().FieldByName(f.Name), i)
+		}
+	}
+	if err := l.Validate(sample.Interface()); err != nil {
+		return fmt.Errorf("sample record: %w", err)
+	}
+	line := l.Format(sample.Interface())
+	if n := len([]rune(line)); n != l.Length() {
+		return fmt.Errorf("formatted record has %d positions, expected %d", n, l.Length())
+	}
+	parsed := reflect.New(l.record)
+	if err := l.Parse(line, parsed.Interface()); err != nil {
+		return fmt.Errorf("parsing formatted record: %w", err)
+	}
+	for _, f := range l.Fields {
+		if f.Type == LayoutFiller {
+			continue
+		}
+		want, got := sample.Elem().FieldByName(f.Name), parsed.Elem().FieldByName(f.Name)
+		if f.format(want) != f.format(got) {
+			return fmt.Errorf("field %s: formatted %q, parsed back %q", f.Name, f.format(want), f.format(got))
+		}
+	}
+	if again := l.Format(parsed.Interface()); again != line {
+		return fmt.Errorf("record changed on round trip:\n%q\n%q", line, again)
+	}
+	return nil
+}
+
+// setSample fills a field with a value taking its whole width, distinct from its neighbours
+// signed fields get negative values, so the sign position is exercised
+func (f LayoutField) setSample(v reflect.Value, index int) {
+	var digits, letters strings.Builder
+	for i := 0; i < f.Width; i++ {
+		digits.WriteByte(byte('1' + (index+i)%9))
+		letters.WriteByte(byte('A' + (index+i)%26))
+	}
+	if len(f.Domain) > 0 {
+		digits.Reset()
+		letters.Reset()
+		digits.WriteString(f.Domain[index%len(f.Domain)])
+		letters.WriteString(f.Domain[index%len(f.Domain)])
+	}
+	if f.Type == LayoutText {
+		v.SetString(letters.String())
+		return
+	}
+	s := digits.String()
+	if len(s) > 18 {
+		s = s[:18]
+	}
+	if f.Signed && len(s) > 1 {
+		s = "-" + s[1:]
+	}
+	n, _ := strconv.ParseInt(s, 10, 64)
+	if f.Type == LayoutDecimal {
+		v.Set(reflect.ValueOf(fromImplied(n, f.Decimals)))
+		return
+	}
+	v.SetInt(n)
+}
+
+// fileLayout selects the layout version of a file on its first record and parses every record with it
+type fileLayout struct {
+	name   string
+	opts   port.ParseOptions
+	enc    Encoding
+	layout *Layout
+}
+
+// newFileLayout creates a new fileLayout instance for a file being parsed in an encoding
+func newFileLayout(name string, opts port.ParseOptions, enc Encoding) *fileLayout {
+	return &fileLayout{name: name, opts: opts, enc: enc}
+}
+
+// Parse reads a record line with the layout version of the file
+// the line must pass CheckLine before any field is read
+func (fl *fileLayout) Parse(line string, record interface{}) error {
+	if fl.layout == nil {
+		l, err := SelectLayout(fl.name, fl.opts, line)
+		if err != nil {
+			return err
+		}
+		fl.layout = l
+	}
+	if err := fl.layout.CheckLine(line, fl.enc); err != nil {
+		return err
+	}
+	return fl.layout.Parse(line, record)
+}
@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// lucrCredSpec is a LUCRCRED layout version as loaded from a layout file, with a filler and wider amounts
+const lucrCredSpec = `
+report: LUCRCRED
+version: test-2026
+effective_from: 2026Q1
+fields:
+  - {name: Year, start: 1, width: 4, type: numeric}
+  - {name: Quarter, start: 5, width: 1, type: numeric, domain: ["1", "2", "3", "4"]}
+  - {name: DiscountRevenue, start: 6, width: 15, type: decimal, decimals: 2}
+  - {name: RentRevenue, start: 21, width: 15, type: decimal, decimals: 2}
+  - {name: OtherRevenue, start: 36, width: 15, type: decimal, decimals: 2, signed: true}
+  - {name: Reserved, start: 51, width: 10, type: filler}
+  - {name: InterchangeCost, start: 61, width: 15, type: decimal, decimals: 2, signed: true}
+  - {name: MarketingCost, start: 76, width: 15, type: decimal, decimals: 2, signed: true}
+  - {name: BrandAccessCost, start: 91, width: 15, type: decimal, decimals: 2, signed: true}
+  - {name: RiskCost, start: 106, width: 15, type: decimal, decimals: 2, signed: true}
+  - {name: ProcessingCost, start: 121, width: 15, type: decimal, decimals: 2, signed: true}
+  - {name: OtherCost, start: 136, width: 15, type: decimal, decimals: 2, signed: true}
+`
+
+func TestLayoutsSelfCheck(t *testing.T) {
+	all := GetLayouts()
+	if len(all) == 0 {
+		t.Fatal("no layout registered")
+	}
+	for _, l := range all {
+		t.Run(l.Name+"/"+l.Version, func(t *testing.T) {
+			if err := l.Check(); err != nil {
+				t.Errorf("Check() error: %v", err)
+			}
+			if err := l.SelfCheck(); err != nil {
+				t.Errorf("SelfCheck() error: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadedLayoutSelfCheck(t *testing.T) {
+	var spec LayoutSpec
+	if err := yaml.Unmarshal([]byte(lucrCredSpec), &spec); err != nil {
+		t.Fatal(err)
+	}
+	builtin := layouts[spec.Report]
+	t.Cleanup(func() { layouts[spec.Report] = builtin })
+	l, err := RegisterLayout(spec)
+	if err != nil {
+		t.Fatalf("RegisterLayout() error: %v", err)
+	}
+	if l.Length() != 150 {
+		t.Errorf("Length() = %d, expected 150", l.Length())
+	}
+	if err := l.Check(); err != nil {
+		t.Errorf("Check() error: %v", err)
+	}
+	if err := l.SelfCheck(); err != nil {
+		t.Errorf("SelfCheck() error: %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package domain
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// OverflowPolicy represents what happens to a text value wider than its field when a record is written
+type OverflowPolicy string
+
+const (
+	// OverflowReject fails the record, so no field is ever shifted or cut
+	OverflowReject OverflowPolicy = "reject"
+	// OverflowTruncate cuts the text to the field width
+	OverflowTruncate OverflowPolicy = "truncate"
+)
+
+// FieldError reports a field of a record that cannot be written or read
+// Start and End are the 1-based positions of the field; Field is empty for a single position of the line
+type FieldError struct {
+	Field string
+	Start int
+	End   int
+	Err   error
+}
+
+// Error returns the description of the field error
+func (e *FieldError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("position %d: %v", e.Start, e.Err)
+	}
+	return fmt.Sprintf("%s positions %d-%d: %v", e.Field, e.Start, e.End, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// overflow is the overflow policy in force, reject unless configured otherwise
+var overflow = OverflowReject
+
+// ParseOverflowPolicy parses an overflow policy name; empty means reject
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+	switch OverflowPolicy(s) {
+	case "", OverflowReject:
+		return OverflowReject, nil
+	case OverflowTruncate:
+		return OverflowTruncate, nil
+	}
+	return "", fmt.Errorf("invalid overflow policy %q: expected reject or truncate", s)
+}
+
+// SetOverflowPolicy sets the overflow policy used when writing records
+func SetOverflowPolicy(policy OverflowPolicy) {
+	overflow = policy
+}
+
+// GetOverflowPolicy returns the overflow policy in force
+func GetOverflowPolicy() OverflowPolicy {
+	return overflow
+}
+
+// FormatLine writes a record as a fixed-width line ready for the file
+// text wider than its field is rejected or truncated by the overflow policy in force;
+// numbers are never truncated, as that would change their value, and are rejected when negative on unsigned fields
+// the encoding policy is then applied to the line, which is checked with CheckLine
+func (l *Layout) FormatLine(record interface{}, enc Encoding) (string, error) {
+	v := reflect.ValueOf(record).Elem()
+	var sb strings.Builder
+	for _, f := range l.Fields {
+		if f.Type == LayoutFiller {
+			sb.WriteString(strings.Repeat(" ", f.Width))
+			continue
+		}
+		s := f.format(v.FieldByName(f.Name))
+		if f.Type != LayoutText && strings.HasPrefix(s, "-") && !f.Signed {
+			return "", &FieldError{Field: f.Name, Start: f.Start, End: f.End(),
+				Err: fmt.Errorf("negative value %s on unsigned field", strings.TrimSpace(s))}
+		}
+		if runes := []rune(s); len(runes) > f.Width {
+			if f.Type != LayoutText || overflow != OverflowTruncate {
+				return "", &FieldError{Field: f.Name, Start: f.Start, End: f.End(),
+					Err: fmt.Errorf("value %q does not fit %d positions", strings.TrimSpace(s), f.Width)}
+			}
+			s = string(runes[:f.Width])
+		}
+		sb.WriteString(s)
+	}
+	line, err := enc.Apply(sb.String())
+	if err != nil {
+		return "", err
+	}
+	if err := l.CheckLine(line, enc); err != nil {
+		return "", err
+	}
+	return line, nil
+}
+
+// CheckLine checks that a line has exactly the positions of the layout
+// and that every character is representable in the charset of the files
+func (l *Layout) CheckLine(line string, enc Encoding) error {
+	n := 0
+	for _, r := range line {
+		n++
+		if !enc.Representable(r) {
+			return &FieldError{Start: n, End: n, Err: fmt.Errorf("character %q is not representable in %s", r, enc.Charset)}
+		}
+	}
+	if n != l.Length() {
+		return fmt.Errorf("%s record has %d positions, expected %d", l.Name, n, l.Length())
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestLayoutFormatLineSign(t *testing.T) {
+	amount := func(s string) decimal.Decimal {
+		return decimal.RequireFromString(s)
+	}
+	tests := []struct {
+		name   string
+		record *LucrCred
+		line   string
+		field  string
+	}{
+		{
+			name: "negative costs",
+			record: &LucrCred{Year: 2025, Quarter: 1, DiscountRevenue: amount("1234.56"),
+				OtherRevenue: amount("-0.01"), InterchangeCost: amount("-12.34"), OtherCost: amount("-999999999.99")},
+			line: "20251" + "000000123456" + "000000000000" + "-00000000001" + "-00000001234" +
+				"000000000000" + "000000000000" + "000000000000" + "000000000000" + "-99999999999",
+		},
+		{
+			name:   "negative value on unsigned field",
+			record: &LucrCred{Year: 2025, Quarter: 1, DiscountRevenue: amount("-12.34")},
+			field:  "DiscountRevenue",
+		},
+		{
+			name:   "widest unsigned value",
+			record: &LucrCred{Year: 2025, Quarter: 1, RentRevenue: amount("9999999999.99")},
+			line: "20251" + "000000000000" + "999999999999" + "000000000000" + "000000000000" +
+				"000000000000" + "000000000000" + "000000000000" + "000000000000" + "000000000000",
+		},
+		{
+			name:   "sign takes a digit position",
+			record: &LucrCred{Year: 2025, Quarter: 1, RiskCost: amount("-9999999999.99")},
+			field:  "RiskCost",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, err := lucrCredLayout.FormatLine(tt.record, DefaultEncoding)
+			if tt.field != "" {
+				var fe *FieldError
+				if !errors.As(err, &fe) || fe.Field != tt.field {
+					t.Fatalf("FormatLine() = %q, %v, expected a field error on %s", line, err, tt.field)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FormatLine() error: %v", err)
+			}
+			if line != tt.line {
+				t.Fatalf("FormatLine() =\n%q, expected\n%q", line, tt.line)
+			}
+			parsed := &LucrCred{}
+			if err := lucrCredLayout.Parse(line, parsed); err != nil {
+				t.Fatalf("Parse() error: %v", err)
+			}
+			if again := lucrCredLayout.Format(parsed); again != line {
+				t.Errorf("round trip changed the line to %q", again)
+			}
+		})
+	}
+}
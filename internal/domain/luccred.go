@@ -1,38 +1,42 @@
 package domain
 
 import (
-	"bufio"
 	"fmt"
-	"os"
 
-	"github.com/ianlopshire/go-fixedwidth"
 	"github.com/lavinas/cadoc6334/internal/port"
-	"golang.org/x/text/encoding/charmap"
+	"github.com/shopspring/decimal"
 )
 
 type LucrCred struct {
-	Year               int64   `fixed:"1,4" gorm:"column:ano"`
-	Quarter            int64   `fixed:"5,5" gorm:"column:trimestre"`
-	DiscountRevenue    float64 `gorm:"column:receitataxadescontobruta;type:numeric(18,2)"`
-	DiscountRevenueInt int64   `fixed:"6,17"`
-	RentRevenue        float64 `gorm:"column:receitaaluguelequipamentosconectividade;type:numeric(18,2)"`
-	RentRevenueInt     int64   `fixed:"18,29"`
-	OtherRevenue       float64 `gorm:"column:receitaoutras"`
-	OtherRevenueInt    int64   `fixed:"30,41"`
-	InterchangeCost    float64 `gorm:"column:custotarifaintercambio"`
-	InterchangeCostInt int64   `fixed:"42,53"`
-	MarketingCost      float64 `gorm:"column:customarketingpropaganda"`
-	MarketingCostInt   int64   `fixed:"54,65"`
-	BrandAccessCost    float64 `gorm:"column:custotaxasacessobandeiras"`
-	BrandAccessCostInt int64   `fixed:"66,77"`
-	RiskCost           float64 `gorm:"column:custorisco"`
-	RiskCostInt        int64   `fixed:"78,89"`
-	ProcessingCost     float64 `gorm:"column:custoprocessamento"`
-	ProcessingCostInt  int64   `fixed:"90,101"`
-	OtherCost          float64 `gorm:"column:custooutros"`
-	OtherCostInt       int64   `fixed:"102,113"`
+	Year            int64           `gorm:"column:ano"`
+	Quarter         int64           `gorm:"column:trimestre"`
+	DiscountRevenue decimal.Decimal `gorm:"column:receitataxadescontobruta;type:numeric(18,2)"`
+	RentRevenue     decimal.Decimal `gorm:"column:receitaaluguelequipamentosconectividade;type:numeric(18,2)"`
+	OtherRevenue    decimal.Decimal `gorm:"column:receitaoutras"`
+	InterchangeCost decimal.Decimal `gorm:"column:custotarifaintercambio"`
+	MarketingCost   decimal.Decimal `gorm:"column:customarketingpropaganda"`
+	BrandAccessCost decimal.Decimal `gorm:"column:custotaxasacessobandeiras"`
+	RiskCost        decimal.Decimal `gorm:"column:custorisco"`
+	ProcessingCost  decimal.Decimal `gorm:"column:custoprocessamento"`
+	OtherCost       decimal.Decimal `gorm:"column:custooutros"`
 }
 
+// lucrCredLayout describes the LUCRCRED.TXT record
+// other revenue and costs may be negative on reversals and adjustments
+var lucrCredLayout = registerLayout("LUCRCRED", &LucrCred{},
+	numericAt("Year", 1, 4),
+	numericAt("Quarter", 5, 1).oneOf("1", "2", "3", "4"),
+	decimalAt("DiscountRevenue", 6, 12, 2),
+	decimalAt("RentRevenue", 18, 12, 2),
+	decimalAt("OtherRevenue", 30, 12, 2).signed(),
+	decimalAt("InterchangeCost", 42, 12, 2).signed(),
+	decimalAt("MarketingCost", 54, 12, 2).signed(),
+	decimalAt("BrandAccessCost", 66, 12, 2).signed(),
+	decimalAt("RiskCost", 78, 12, 2).signed(),
+	decimalAt("ProcessingCost", 90, 12, 2).signed(),
+	decimalAt("OtherCost", 102, 12, 2).signed(),
+)
+
 // NewLucrCred creates a new LucrCred instance
 func NewLucrCred() *LucrCred {
 	return &LucrCred{}
@@ -45,29 +49,7 @@ func (l *LucrCred) GetName() string {
 
 // Format marshals the LucrCred struct into a fixed-width format.
 func (l *LucrCred) Format() string {
-	ret := ""
-	ret += fmt.Sprintf("%04d", l.Year)
-	ret += fmt.Sprintf("%01d", l.Quarter)
-	// Convert float64 fields to int representation
-	l.DiscountRevenueInt = int64(l.DiscountRevenue*100 + 0.5)
-	l.RentRevenueInt = int64(l.RentRevenue*100 + 0.5)
-	l.OtherRevenueInt = int64(l.OtherRevenue*100 + 0.5)
-	l.InterchangeCostInt = int64(l.InterchangeCost*100 + 0.5)
-	l.MarketingCostInt = int64(l.MarketingCost*100 + 0.5)
-	l.BrandAccessCostInt = int64(l.BrandAccessCost*100 + 0.5)
-	l.RiskCostInt = int64(l.RiskCost*100 + 0.5)
-	l.ProcessingCostInt = int64(l.ProcessingCost*100 + 0.5)
-	l.OtherCostInt = int64(l.OtherCost*100 + 0.5)
-	ret += fmt.Sprintf("%012d", l.DiscountRevenueInt)
-	ret += fmt.Sprintf("%012d", l.RentRevenueInt)
-	ret += fmt.Sprintf("%012d", l.OtherRevenueInt)
-	ret += fmt.Sprintf("%012d", l.InterchangeCostInt)
-	ret += fmt.Sprintf("%012d", l.MarketingCostInt)
-	ret += fmt.Sprintf("%012d", l.BrandAccessCostInt)
-	ret += fmt.Sprintf("%012d", l.RiskCostInt)
-	ret += fmt.Sprintf("%012d", l.ProcessingCostInt)
-	ret += fmt.Sprintf("%012d", l.OtherCostInt)
-	return ret
+	return lucrCredLayout.Format(l)
 }
 
 // Validate validates the LucrCred information.
@@ -78,34 +60,31 @@ func (l *LucrCred) Validate() error {
 	if l.Quarter <= 0 {
 		return fmt.Errorf("invalid quarter in LucrCred")
 	}
-	if l.DiscountRevenue <= 0 {
+	if !l.DiscountRevenue.IsPositive() {
 		return fmt.Errorf("invalid discount revenue in LucrCred")
 	}
-	if l.RentRevenue <= 0 {
+	if !l.RentRevenue.IsPositive() {
 		return fmt.Errorf("invalid rent revenue in LucrCred")
 	}
-	if l.OtherRevenue <= 0 {
+	if l.OtherRevenue.IsZero() {
 		return fmt.Errorf("invalid other revenue in LucrCred")
 	}
-	if l.InterchangeCost <= 0 {
+	if l.InterchangeCost.IsZero() {
 		return fmt.Errorf("invalid interchange cost in LucrCred")
 	}
-	if l.MarketingCost <= 0 {
+	if l.MarketingCost.IsZero() {
 		return fmt.Errorf("invalid marketing cost in LucrCred")
 	}
-	if l.BrandAccessCost <= 0 {
+	if l.BrandAccessCost.IsZero() {
 		return fmt.Errorf("invalid brand access cost in LucrCred")
 	}
-	if l.RiskCost < 0 {
-		return fmt.Errorf("invalid risk cost in LucrCred")
-	}
-	if l.ProcessingCost <= 0 {
+	if l.ProcessingCost.IsZero() {
 		return fmt.Errorf("invalid processing cost in LucrCred")
 	}
-	if l.OtherCost <= 0 {
+	if l.OtherCost.IsZero() {
 		return fmt.Errorf("invalid other cost in LucrCred")
 	}
-	return nil
+	return lucrCredLayout.Validate(l)
 }
 
 // TableName returns the table name for the LucrCred struct
@@ -119,14 +98,17 @@ func (l *LucrCred) GetKey() string {
 }
 
 // GetDB retrieves all LucrCred records.
-func (l *LucrCred) GetDB(repo port.Repository) (map[string]port.Report, error) {
+func (l *LucrCred) GetDB(repo port.Repository, period port.Period) (map[string]port.Report, error) {
 	var records []*LucrCred
-	err := repo.FindAll(&records, 0, 0, "")
+	err := repo.FindAll(&records, 0, 0, "", "ano = ? AND trimestre = ?", period.Year, period.Quarter)
 	if err != nil {
 		return nil, err
 	}
 	ret := make(map[string]port.Report)
 	for _, r := range records {
+		if err := period.Check(r.Year, r.Quarter); err != nil {
+			return nil, fmt.Errorf("key %s: %w", r.GetKey(), err)
+		}
 		ret[r.GetKey()] = r
 	}
 	return ret, nil
@@ -134,75 +116,55 @@ func (l *LucrCred) GetDB(repo port.Repository) (map[string]port.Report, error) {
 
 // Parse parses a fixed-width string into a LucrCred struct
 func (l *LucrCred) Parse(line string) error {
-	err := fixedwidth.Unmarshal([]byte(line), l)
-	if err != nil {
-		return err
-	}
-	l.DiscountRevenue = float64(l.DiscountRevenueInt) / float64(100)
-	l.RentRevenue = float64(l.RentRevenueInt) / float64(100)
-	l.OtherRevenue = float64(l.OtherRevenueInt) / float64(100)
-	l.InterchangeCost = float64(l.InterchangeCostInt) / float64(100)
-	l.MarketingCost = float64(l.MarketingCostInt) / float64(100)
-	l.BrandAccessCost = float64(l.BrandAccessCostInt) / float64(100)
-	l.RiskCost = float64(l.RiskCostInt) / float64(100)
-	l.ProcessingCost = float64(l.ProcessingCostInt) / float64(100)
-	l.OtherCost = float64(l.OtherCostInt) / float64(100)
-	return nil
+	return lucrCredLayout.Parse(line, l)
 }
 
 // String returns a string representation of the LucrCred struct
 func (l *LucrCred) String() string {
-	return fmt.Sprintf("Year: %d, Quarter: %d, DiscountRevenue: %.2f, RentRevenue: %.2f, OtherRevenue: %.2f, InterchangeCost: %.2f, MarketingCost: %.2f, BrandAccessCost: %.2f, RiskCost: %.2f, ProcessingCost: %.2f, OtherCost: %.2f",
-		l.Year, l.Quarter, l.DiscountRevenue, l.RentRevenue, l.OtherRevenue, l.InterchangeCost, l.MarketingCost, l.BrandAccessCost, l.RiskCost, l.ProcessingCost, l.OtherCost)
+	return fmt.Sprintf("Year: %d, Quarter: %d, DiscountRevenue: %s, RentRevenue: %s, OtherRevenue: %s, InterchangeCost: %s, MarketingCost: %s, BrandAccessCost: %s, RiskCost: %s, ProcessingCost: %s, OtherCost: %s",
+		l.Year, l.Quarter, l.DiscountRevenue.StringFixed(2), l.RentRevenue.StringFixed(2), l.OtherRevenue.StringFixed(2), l.InterchangeCost.StringFixed(2), l.MarketingCost.StringFixed(2), l.BrandAccessCost.StringFixed(2), l.RiskCost.StringFixed(2), l.ProcessingCost.StringFixed(2), l.OtherCost.StringFixed(2))
+}
+
+// GetFields returns the LucrCred fields for field level comparison
+func (l *LucrCred) GetFields() []port.Field {
+	return []port.Field{
+		intField("Year", l.Year),
+		intField("Quarter", l.Quarter),
+		amountField("DiscountRevenue", l.DiscountRevenue),
+		amountField("RentRevenue", l.RentRevenue),
+		amountField("OtherRevenue", l.OtherRevenue),
+		amountField("InterchangeCost", l.InterchangeCost),
+		amountField("MarketingCost", l.MarketingCost),
+		amountField("BrandAccessCost", l.BrandAccessCost),
+		amountField("RiskCost", l.RiskCost),
+		amountField("ProcessingCost", l.ProcessingCost),
+		amountField("OtherCost", l.OtherCost),
+	}
 }
 
 // ParseLucrCredFile parses the LucrCred.TXT file and returns a slice of LucrCred records.
-func (l *LucrCred) ParseLucrCredFile(filePath string) ([]*LucrCred, error) {
-	file, err := os.Open(filePath)
+func (l *LucrCred) ParseLucrCredFile(filePath string, opts port.ParseOptions) ([]*LucrCred, error) {
+	reader, err := openFile(filePath, "LUCRCRED", opts)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
-
+	// read records
 	var records []*LucrCred
-	decoder := charmap.ISO8859_1.NewDecoder()
-	decodedReader := decoder.Reader(file)
-	scanner := bufio.NewScanner(decodedReader)
-	// Read and parse header
-	if !scanner.Scan() {
-		return nil, fmt.Errorf("file is empty")
-	}
-	headerLine := scanner.Text()
-	header := &RankingHeader{}
-	_, err = header.Parse(headerLine)
-	if err != nil {
-		return nil, fmt.Errorf("error parsing header: %w", err)
-	}
-	// Read and parse records
-	count := 0
-	for scanner.Scan() {
-		line := scanner.Text()
-		LucrCred := NewLucrCred()
-		err := LucrCred.Parse(line)
-		if err != nil {
-			return nil, fmt.Errorf("error parsing line: %w", err)
+	for reader.Next() {
+		record := NewLucrCred()
+		if reader.Parse(record) && reader.CheckPeriod(record.Year, record.Quarter) {
+			records = append(records, record)
 		}
-		records = append(records, LucrCred)
-		count++
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
 	}
-	// Validate header
-	if err := header.Validate("LUCRCRED", int64(count)); err != nil {
+	if err := reader.Close(); err != nil {
 		return nil, err
 	}
 	return records, nil
 }
 
 // GetParsedFile retrieves and parses the LucrCred.TXT file.
-func (l *LucrCred) GetParsedFile(filename string) (map[string]port.Report, error) {
-	records, err := l.ParseLucrCredFile(filename)
+func (l *LucrCred) GetParsedFile(filename string, opts port.ParseOptions) (map[string]port.Report, error) {
+	records, err := l.ParseLucrCredFile(filename, opts)
 	if err != nil {
 		return nil, err
 	}
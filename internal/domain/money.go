@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// RoundingMode represents how amounts and rates are rounded to the decimal places written on the files
+type RoundingMode string
+
+const (
+	// RoundHalfUp rounds halves away from zero (0.125 -> 0.13, -0.125 -> -0.13)
+	RoundHalfUp RoundingMode = "half_up"
+	// RoundHalfEven rounds halves to the even digit, a.k.a. banker's rounding (0.125 -> 0.12)
+	RoundHalfEven RoundingMode = "half_even"
+)
+
+// rounding is the rounding mode in force, half up unless configured otherwise
+var rounding = RoundHalfUp
+
+// ParseRoundingMode parses a rounding mode name; empty means half up
+func ParseRoundingMode(s string) (RoundingMode, error) {
+	switch RoundingMode(s) {
+	case "", RoundHalfUp:
+		return RoundHalfUp, nil
+	case RoundHalfEven, "bankers":
+		return RoundHalfEven, nil
+	}
+	return "", fmt.Errorf("invalid rounding mode %q: expected half_up or half_even", s)
+}
+
+// SetRoundingMode sets the rounding mode used when formatting and comparing amounts and rates
+func SetRoundingMode(mode RoundingMode) {
+	rounding = mode
+}
+
+// GetRoundingMode returns the rounding mode in force
+func GetRoundingMode() RoundingMode {
+	return rounding
+}
+
+// roundDecimal rounds a value to the given decimal places with the rounding mode in force
+func roundDecimal(d decimal.Decimal, places int32) decimal.Decimal {
+	if rounding == RoundHalfEven {
+		return d.RoundBank(places)
+	}
+	return d.Round(places)
+}
+
+// toImplied converts a value to its integer representation with implied decimal places
+// e.g. 1234.565 with 2 places is 123457 (half up)
+func toImplied(d decimal.Decimal, places int32) int64 {
+	return roundDecimal(d, places).Shift(places).IntPart()
+}
+
+// fromImplied converts an integer with implied decimal places back to its value
+// e.g. 123457 with 2 places is 1234.57
+func fromImplied(v int64, places int32) decimal.Decimal {
+	return decimal.New(v, -places)
+}
@@ -0,0 +1,106 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// withRounding sets the rounding mode for a test and restores the previous one when it ends
+func withRounding(t *testing.T, mode RoundingMode) {
+	previous := GetRoundingMode()
+	SetRoundingMode(mode)
+	t.Cleanup(func() { SetRoundingMode(previous) })
+}
+
+func TestToImpliedRounding(t *testing.T) {
+	tests := []struct {
+		value    string
+		halfUp   int64
+		halfEven int64
+	}{
+		{value: "0.125", halfUp: 13, halfEven: 12},
+		{value: "0.135", halfUp: 14, halfEven: 14},
+		{value: "-0.125", halfUp: -13, halfEven: -12},
+		{value: "-0.135", halfUp: -14, halfEven: -14},
+		{value: "-0.124", halfUp: -12, halfEven: -12},
+		{value: "-1234567890.005", halfUp: -123456789001, halfEven: -123456789000},
+		{value: "9999999999.994", halfUp: 999999999999, halfEven: 999999999999},
+		{value: "2.675", halfUp: 268, halfEven: 268},
+	}
+	for _, mode := range []RoundingMode{RoundHalfUp, RoundHalfEven} {
+		t.Run(string(mode), func(t *testing.T) {
+			withRounding(t, mode)
+			for _, tt := range tests {
+				want := tt.halfUp
+				if mode == RoundHalfEven {
+					want = tt.halfEven
+				}
+				if got := toImplied(decimal.RequireFromString(tt.value), 2); got != want {
+					t.Errorf("toImplied(%s, 2) = %d, expected %d", tt.value, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestImpliedSymmetry(t *testing.T) {
+	values := []int64{0, 1, -1, 99, -100, 123456789012, -987654321098, 999999999999999999}
+	for _, places := range []int32{0, 2, 4} {
+		for _, v := range values {
+			if got := toImplied(fromImplied(v, places), places); got != v {
+				t.Errorf("toImplied(fromImplied(%d, %d)) = %d", v, places, got)
+			}
+		}
+	}
+	for _, s := range []string{"0.01", "-0.01", "1234567890.12", "-9999999999.99"} {
+		d := decimal.RequireFromString(s)
+		if got := fromImplied(toImplied(d, 2), 2); !got.Equal(d) {
+			t.Errorf("fromImplied(toImplied(%s, 2)) = %s", s, got)
+		}
+	}
+}
+
+func TestLucrCredRoundTrip(t *testing.T) {
+	amount := decimal.RequireFromString
+	record := &LucrCred{
+		Year:            2025,
+		Quarter:         3,
+		DiscountRevenue: amount("9876543210.99"),
+		RentRevenue:     amount("1234567890.01"),
+		OtherRevenue:    amount("-999999999.99"),
+		InterchangeCost: amount("4567890123.45"),
+		MarketingCost:   amount("0.01"),
+		BrandAccessCost: amount("-123456789.10"),
+		RiskCost:        amount("9999999999.99"),
+		ProcessingCost:  amount("3000000000.03"),
+		OtherCost:       amount("-0.01"),
+	}
+	line, err := lucrCredLayout.FormatLine(record, DefaultEncoding)
+	if err != nil {
+		t.Fatalf("FormatLine() error: %v", err)
+	}
+	parsed := &LucrCred{}
+	if err := lucrCredLayout.Parse(line, parsed); err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	pairs := []struct {
+		name      string
+		want, got decimal.Decimal
+	}{
+		{"DiscountRevenue", record.DiscountRevenue, parsed.DiscountRevenue},
+		{"RentRevenue", record.RentRevenue, parsed.RentRevenue},
+		{"OtherRevenue", record.OtherRevenue, parsed.OtherRevenue},
+		{"InterchangeCost", record.InterchangeCost, parsed.InterchangeCost},
+		{"MarketingCost", record.MarketingCost, parsed.MarketingCost},
+		{"BrandAccessCost", record.BrandAccessCost, parsed.BrandAccessCost},
+		{"RiskCost", record.RiskCost, parsed.RiskCost},
+		{"ProcessingCost", record.ProcessingCost, parsed.ProcessingCost},
+		{"OtherCost", record.OtherCost, parsed.OtherCost},
+	}
+	for _, p := range pairs {
+		if !p.got.Equal(p.want) {
+			t.Errorf("%s: parsed %s, expected %s", p.name, p.got.StringFixed(2), p.want.StringFixed(2))
+		}
+	}
+}
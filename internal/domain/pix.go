@@ -1,38 +1,74 @@
 package domain
 
 import (
+	"errors"
 	"fmt"
-	"github.com/shopspring/decimal"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/ianlopshire/go-fixedwidth"
 	"github.com/lavinas/cadoc6334/internal/port"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// pixLength is the number of positions of a PIX DIMP transaction record
+	pixLength = 158
+	// pixDate and pixTime are the formats of dates and times on the PIX DIMP files
+	pixDate = "2006/01/02"
+	pixTime = "15:04:05"
+	// pixFilePrefix and pixFileDate name the PIX DIMP daily files, e.g. bh_transacoes_2025-07-01.txt
+	pixFilePrefix = "bh_transacoes_"
+	pixFileDate   = "2006-01-02"
+	pixFileSuffix = ".txt"
 )
 
+// PixBatchSize is the number of PIX records read from the database at a time
+const PixBatchSize = 10000
+
+// pixOrder is the order of the PIX records on the files; the row id breaks the ties of rows sharing
+// date and source NSU, making the order unique for keyset pagination
+const pixOrder = "datatransacao, nsu, ctid"
+
+// pixColumns reads the row id along with every column, as ctid is not part of "*"
+// the alias differs from ctid so that ordering by ctid sorts the row ids and not their text
+const pixColumns = "*, ctid::text AS row_id"
+
+// PixFilePattern matches the names of the PIX DIMP daily files
+const PixFilePattern = pixFilePrefix + "*" + pixFileSuffix
+
 // Pix represents the PIX record structure.
+// the positions of each field on the file are given by format and Parse
+// NSU and CodigoAutorizacao are derived from NSUSource by the PixTransformer when read from the database
+// RowID is the physical row id (ctid) of a record read from the database, identifying rows sharing every column
+// LeftOut holds the records GetDB left out of its result
 type Pix struct {
-	RecordType         string          `fixed:"1,1" gorm:"column:recordtype"`
-	CodigoCliente      string          `fixed:"2,16" gorm:"column:codigocliente"`
-	DataMovimento      time.Time       `fixed:"17,26" gorm:"column:data_movimento"`
-	DataTransacao      time.Time       `fixed:"27,36" gorm:"column:datatransacao"`
-	DataProcessamento  time.Time       `fixed:"37,46" gorm:"column:dataprocessamento"`
-	CodigoBandeira     string          `fixed:"47,49" gorm:"column:codigobandeira"`
-	CodigoProduto      string          `fixed:"50,51" gorm:"column:produto"`
-	TipoParcelamento   string          `fixed:"52,52" gorm:"column:tp_parcela"`
-	TipoTransacao      string          `fixed:"53,53" gorm:"column:tp_origem"`
-	PlanoPagamento     string          `fixed:"54,55" gorm:"column:nu_parcela"`
-	ValorBrutoOriginal decimal.Decimal `fixed:"56,72" gorm:"column:nu_valor"`
-	TaxaMDROriginal    string          `fixed:"73,77" gorm:"column:nu_porc_transacao"`
-	ValorMDROriginal   decimal.Decimal `fixed:"78,94" gorm:"column:valor_mdr"`
-	TipoTecnologia     string          `fixed:"95,96" gorm:"column:tipo_tecnologia"`
-	NumeroTerminal     string          `fixed:"97,104" gorm:"column:terminal_id"`
-	CodigoAutorizacao  string          `fixed:"105,110" gorm:"column:auth_target"`
-	NSU                string          `fixed:"111,130" gorm:"column:nsu_target"`
-	NumeroECFPADQ      string          `fixed:"131,145" gorm:"column:numero_ec_fp_adq"`
-	ArranjoPagamentoFP string          `fixed:"146,148" gorm:"column:empty_field"`
-	CodigoFormaEntrada string          `fixed:"149,150" gorm:"column:forma_entrada"`
-	Hora               time.Time       `fixed:"151,158" gorm:"column:hora_transacao;type:time"`
+	RecordType         string          `gorm:"column:recordtype"`
+	CodigoCliente      string          `gorm:"column:codigocliente"`
+	DataMovimento      time.Time       `gorm:"column:data_movimento"`
+	DataTransacao      time.Time       `gorm:"column:datatransacao"`
+	DataProcessamento  time.Time       `gorm:"column:dataprocessamento"`
+	CodigoBandeira     string          `gorm:"column:codigobandeira"`
+	CodigoProduto      string          `gorm:"column:produto"`
+	TipoParcelamento   string          `gorm:"column:tp_parcela"`
+	TipoTransacao      string          `gorm:"column:tp_origem"`
+	PlanoPagamento     string          `gorm:"column:nu_parcela"`
+	ValorBrutoOriginal decimal.Decimal `gorm:"column:nu_valor"`
+	TaxaMDROriginal    string          `gorm:"column:nu_porc_transacao"`
+	ValorMDROriginal   decimal.Decimal `gorm:"column:valor_mdr"`
+	TipoTecnologia     string          `gorm:"column:tipo_tecnologia"`
+	NumeroTerminal     string          `gorm:"column:terminal_id"`
+	CodigoAutorizacao  string          `gorm:"-"`
+	NSU                string          `gorm:"-"`
+	NumeroECFPADQ      string          `gorm:"column:numero_ec_fp_adq"`
+	ArranjoPagamentoFP string          `gorm:"column:empty_field"`
+	CodigoFormaEntrada string          `gorm:"column:forma_entrada"`
+	Hora               time.Time       `gorm:"column:hora_transacao;type:time"`
+	NSUSource          string          `gorm:"column:nsu"`
 	Duplicated         bool            `gorm:"column:duplicated"`
+	RowID              string          `gorm:"column:row_id;->"`
+	LeftOut            *PixLeftOut     `gorm:"-"`
 }
 
 // NewPix creates a new Pix instance
@@ -46,32 +82,47 @@ func (p *Pix) GetName() string {
 }
 
 // Format marshals the Pix struct into a fixed-width format.
+// values that cannot be normalized are written whole; FormatLine rejects them
 func (p *Pix) Format() string {
-	ret := ""
-	ret += fmt.Sprintf("%-1s", p.RecordType)
-	ret += fmt.Sprintf("%-15s", p.CodigoCliente)
-	ret += p.DataMovimento.Format("2006/01/02")
-	ret += p.DataTransacao.Format("2006/01/02")
-	ret += p.DataProcessamento.Format("2006/01/02")
-	ret += fmt.Sprintf("%-3s", p.CodigoBandeira)
-	ret += fmt.Sprintf("%-2s", p.CodigoProduto)
-	ret += fmt.Sprintf("%-1s", p.TipoParcelamento)
-	ret += fmt.Sprintf("%-1s", p.TipoTransacao)
-	ret += fmt.Sprintf("%-2s", p.PlanoPagamento)
-	vbint := p.ValorBrutoOriginal.Mul(decimal.NewFromInt(100)).IntPart()
-	ret += fmt.Sprintf("%017d", vbint)
-	ret += fmt.Sprintf("%05s", p.TaxaMDROriginal)
-	vmdr := p.ValorMDROriginal.Mul(decimal.NewFromInt(100)).IntPart()
-	ret += fmt.Sprintf("%017d", vmdr)
-	ret += fmt.Sprintf("%-2s", p.TipoTecnologia)
-	ret += fmt.Sprintf("%-8s", p.NumeroTerminal)
-	ret += fmt.Sprintf("%-6s", p.CodigoAutorizacao[:6])
-	ret += fmt.Sprintf("%-20s", p.NSU[:20])
-	ret += fmt.Sprintf("%-15s", p.NumeroECFPADQ)
-	ret += fmt.Sprintf("%-3s", p.ArranjoPagamentoFP)
-	ret += fmt.Sprintf("%-2s", p.CodigoFormaEntrada)
-	ret += p.Hora.Format("15:04:05")
-	return ret
+	return p.format().line()
+}
+
+// FormatLine writes the record as a fixed-width line ready for the file
+// a field that cannot be normalized to its positions is reported as a *FieldError
+func (p *Pix) FormatLine() (string, error) {
+	pf := p.format()
+	if pf.err != nil {
+		return "", pf.err
+	}
+	return pf.line(), nil
+}
+
+// format normalizes every field to its positions
+// the authorization code and the NSU are required and cut to their width; other text follows the overflow policy
+func (p *Pix) format() *pixFormatter {
+	pf := newPixFormatter()
+	pf.text("RecordType", p.RecordType, 1, 1)
+	pf.text("CodigoCliente", p.CodigoCliente, 2, 16)
+	pf.time(p.DataMovimento, pixDate)
+	pf.time(p.DataTransacao, pixDate)
+	pf.time(p.DataProcessamento, pixDate)
+	pf.text("CodigoBandeira", p.CodigoBandeira, 47, 49)
+	pf.text("CodigoProduto", p.CodigoProduto, 50, 51)
+	pf.text("TipoParcelamento", p.TipoParcelamento, 52, 52)
+	pf.text("TipoTransacao", p.TipoTransacao, 53, 53)
+	pf.text("PlanoPagamento", p.PlanoPagamento, 54, 55)
+	pf.amount("ValorBrutoOriginal", p.ValorBrutoOriginal, 56, 72)
+	pf.rate("TaxaMDROriginal", p.TaxaMDROriginal, 73, 77)
+	pf.amount("ValorMDROriginal", p.ValorMDROriginal, 78, 94)
+	pf.text("TipoTecnologia", p.TipoTecnologia, 95, 96)
+	pf.text("NumeroTerminal", p.NumeroTerminal, 97, 104)
+	pf.cut("CodigoAutorizacao", p.CodigoAutorizacao, 105, 110)
+	pf.cut("NSU", p.NSU, 111, 130)
+	pf.text("NumeroECFPADQ", p.NumeroECFPADQ, 131, 145)
+	pf.text("ArranjoPagamentoFP", p.ArranjoPagamentoFP, 146, 148)
+	pf.text("CodigoFormaEntrada", p.CodigoFormaEntrada, 149, 150)
+	pf.time(p.Hora, pixTime)
+	return pf
 }
 
 // Validate validates the Pix information.
@@ -95,47 +146,87 @@ func (p *Pix) GetKey() string {
 	return fmt.Sprintf("%s|%s", p.DataTransacao.Format("2006-01-02"), p.NSU)
 }
 
-// GetDB returns the database connection.
-func (p *Pix) GetDB(repo port.Repository) (map[string]port.Report, error) {
-	var records []*Pix
-	err := repo.FindAll(&records, 0, 0, "datatransacao")
+// GetDB returns the PIX records with transaction date inside the reference period; a zero period reads every date
+// duplicates by the default key and records the transformer rejects are left out and kept on LeftOut
+func (p *Pix) GetDB(repo port.Repository, period port.Period) (map[string]port.Report, error) {
+	var from, to time.Time
+	if !period.IsZero() {
+		from, to = period.Start(), period.End().AddDate(0, 0, -1)
+	}
+	duplicates, _ := NewPixDuplicates(nil)
+	result, rejected, err := pixRead(pixRange(repo, from, to), duplicates)
 	if err != nil {
 		return nil, err
 	}
-	result := make(map[string]port.Report)
-	for _, record := range records {
-		if record.Duplicated {
-			continue
-		}
-		result[record.GetKey()] = record
-	}
+	p.LeftOut = &PixLeftOut{Duplicates: duplicates.Found, Rejected: rejected}
 	return result, nil
 }
 
-// GetDB returns the database connection.
-func (p *Pix) GetDBOrdered(repo port.Repository) ([]port.Report, error) {
-	var records []*Pix
-	err := repo.FindAll(&records, 0, 0, "datatransacao")
-	if err != nil {
-		return nil, err
+// EachDBOrdered calls fn for every PIX record not flagged as duplicated, ordered by transaction date and source NSU
+// from and to restrict the transaction dates, both inclusive; a zero value leaves the range open on that side
+// records are read batchSize at a time with keyset pagination on (datatransacao, nsu, ctid), so memory
+// does not grow with the table; an error returned by fn stops the iteration and is returned
+func (p *Pix) EachDBOrdered(repo port.Repository, from time.Time, to time.Time, batchSize int, fn func(record *Pix) error) error {
+	if batchSize <= 0 {
+		batchSize = PixBatchSize
 	}
-	var result []port.Report
-	for _, record := range records {
-		if record.Duplicated {
-			continue
+	repo = pixRange(repo, from, to).Select(pixColumns)
+	var last *Pix
+	for {
+		var records []*Pix
+		var err error
+		if last == nil {
+			err = repo.FindAll(&records, batchSize, 0, pixOrder)
+		} else {
+			err = repo.FindAll(&records, batchSize, 0, pixOrder, "(datatransacao, nsu, ctid) > (?, ?, ?::tid)", last.DataTransacao, last.NSUSource, last.RowID)
+		}
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			if record.Duplicated {
+				continue
+			}
+			if err := fn(record); err != nil {
+				return err
+			}
 		}
-		result = append(result, record)
+		if len(records) < batchSize {
+			return nil
+		}
+		last = records[len(records)-1]
 	}
-	return result, nil
 }
 
-// Parse parses the Pix data from a fixed-width file.
+// Parse parses the Pix data from a fixed-width line
+// errors on a field are of type *FieldError
 func (p *Pix) Parse(line string) error {
-	err := fixedwidth.Unmarshal([]byte(line), p)
-	if err != nil {
-		return err
+	pp := newPixParser(line)
+	if n := len(pp.runes); n != pixLength {
+		return fmt.Errorf("PIX record has %d positions, expected %d", n, pixLength)
 	}
-	return nil
+	p.RecordType = pp.text(1, 1)
+	p.CodigoCliente = pp.text(2, 16)
+	p.DataMovimento = pp.time("DataMovimento", 17, 26, pixDate)
+	p.DataTransacao = pp.time("DataTransacao", 27, 36, pixDate)
+	p.DataProcessamento = pp.time("DataProcessamento", 37, 46, pixDate)
+	p.CodigoBandeira = pp.text(47, 49)
+	p.CodigoProduto = pp.text(50, 51)
+	p.TipoParcelamento = pp.text(52, 52)
+	p.TipoTransacao = pp.text(53, 53)
+	p.PlanoPagamento = pp.text(54, 55)
+	p.ValorBrutoOriginal = fromImplied(pp.number("ValorBrutoOriginal", 56, 72), 2)
+	p.TaxaMDROriginal = GetPixRate().parsed(pp.text(73, 77))
+	p.ValorMDROriginal = fromImplied(pp.number("ValorMDROriginal", 78, 94), 2)
+	p.TipoTecnologia = pp.text(95, 96)
+	p.NumeroTerminal = pp.text(97, 104)
+	p.CodigoAutorizacao = pp.text(105, 110)
+	p.NSU = pp.text(111, 130)
+	p.NumeroECFPADQ = pp.text(131, 145)
+	p.ArranjoPagamentoFP = pp.text(146, 148)
+	p.CodigoFormaEntrada = pp.text(149, 150)
+	p.Hora = pp.time("Hora", 151, 158, pixTime)
+	return pp.err
 }
 
 // String returns a string representation of the Pix struct.
@@ -144,21 +235,215 @@ func (p *Pix) String() string {
 		p.RecordType, p.CodigoCliente, p.DataMovimento.Format("2006-01-02"), p.DataTransacao.Format("2006-01-02"), p.DataProcessamento.Format("2006-01-02"), p.CodigoBandeira, p.CodigoProduto, p.TipoParcelamento, p.TipoTransacao, p.PlanoPagamento, p.ValorBrutoOriginal.String(), p.TaxaMDROriginal, p.ValorMDROriginal.String(), p.TipoTecnologia, p.NumeroTerminal, p.CodigoAutorizacao, p.NSU, p.NumeroECFPADQ, p.ArranjoPagamentoFP, p.CodigoFormaEntrada, p.Hora.Format("15:04:05"))
 }
 
-// ParsePixFile parses the PIX.TXT file and returns a slice of Pix records.
-func (p *Pix) ParsePixFile(lines []string) ([]*Pix, error) {
+// GetFields returns the Pix fields for field level comparison
+func (p *Pix) GetFields() []port.Field {
+	return []port.Field{
+		textField("RecordType", p.RecordType),
+		textField("CodigoCliente", p.CodigoCliente),
+		textField("DataMovimento", p.DataMovimento.Format("2006-01-02")),
+		textField("DataTransacao", p.DataTransacao.Format("2006-01-02")),
+		textField("DataProcessamento", p.DataProcessamento.Format("2006-01-02")),
+		textField("CodigoBandeira", p.CodigoBandeira),
+		textField("CodigoProduto", p.CodigoProduto),
+		textField("TipoParcelamento", p.TipoParcelamento),
+		textField("TipoTransacao", p.TipoTransacao),
+		textField("PlanoPagamento", p.PlanoPagamento),
+		amountField("ValorBrutoOriginal", p.ValorBrutoOriginal),
+		p.rateField(),
+		amountField("ValorMDROriginal", p.ValorMDROriginal),
+		textField("TipoTecnologia", p.TipoTecnologia),
+		textField("NumeroTerminal", p.NumeroTerminal),
+		textField("CodigoAutorizacao", p.CodigoAutorizacao),
+		textField("NSU", p.NSU),
+		textField("NumeroECFPADQ", p.NumeroECFPADQ),
+		textField("ArranjoPagamentoFP", p.ArranjoPagamentoFP),
+		textField("CodigoFormaEntrada", p.CodigoFormaEntrada),
+		textField("Hora", p.Hora.Format("15:04:05")),
+	}
+}
+
+// rateField returns the MDR rate for comparison, so rates written with or without the decimal separator match
+func (p *Pix) rateField() port.Field {
+	d, err := GetPixRate().Value(p.TaxaMDROriginal)
+	if err != nil {
+		return textField("TaxaMDROriginal", p.TaxaMDROriginal)
+	}
+	return rateField("TaxaMDROriginal", d)
+}
+
+// ParsePixFile parses a PIX DIMP daily file into its transaction records
+// the file starts with the H header holding the transaction date of every record and ends with
+// the T trailer holding the number of records and, on the totals layout, the value sums; both are checked
+func (p *Pix) ParsePixFile(filename string, opts port.ParseOptions) ([]*Pix, error) {
+	reader, err := newFileReader(filename, "PIX", opts)
+	if err != nil {
+		return nil, err
+	}
 	var records []*Pix
-	for _, line := range lines {
-		var pix Pix
-		err := pix.Parse(line)
-		if err != nil {
-			return nil, err
+	var header *PixHeader
+	var trailer *PixTrailer
+	computed := NewPixTrailer(PixTrailerTotals)
+	for line := 1; reader.Next(); line++ {
+		switch {
+		case line == 1:
+			header = &PixHeader{}
+			if !reader.Apply(header.Parse) {
+				header = nil
+			}
+		case trailer != nil:
+			reader.Check(fmt.Errorf("record after the trailer"))
+		case strings.HasPrefix(reader.Text(), pixTrailerType):
+			trailer = &PixTrailer{}
+			if reader.Apply(trailer.Parse) && reader.Complete() {
+				reader.Check(trailer.Check(computed))
+			}
+		default:
+			record := &Pix{}
+			if reader.Apply(record.Parse) && (header == nil || reader.Check(header.Check(record))) {
+				records = append(records, record)
+				computed.Add(record)
+			}
 		}
-		records = append(records, &pix)
+	}
+	if trailer == nil && reader.Complete() {
+		reader.Check(fmt.Errorf("missing trailer record"))
+	}
+	if err := reader.Close(); err != nil {
+		return nil, err
 	}
 	return records, nil
 }
 
-// GetParsePixFile parses the PIX.TXT file and returns a slice of Pix records.
-func (p *Pix) GetParsedFile(filename string) (map[string]port.Report, error) {
-	return nil, nil
+// GetParsedFile parses a PIX DIMP daily file and maps its records by key
+// a record with the key of an earlier one of the file is left out and reported through opts.Warn
+func (p *Pix) GetParsedFile(filename string, opts port.ParseOptions) (map[string]port.Report, error) {
+	records, err := p.ParsePixFile(filename, opts)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string]port.Report)
+	dups := 0
+	for _, r := range records {
+		key := r.GetKey()
+		if _, ok := ret[key]; ok {
+			dups++
+			if opts.Warn != nil {
+				opts.Warn("file record with key %s repeats an earlier record, left out", key)
+			}
+			continue
+		}
+		ret[key] = r
+	}
+	if dups > 0 && opts.Warn != nil {
+		opts.Warn("%d duplicate file record(s) left out", dups)
+	}
+	return ret, nil
+}
+
+// GetDBDay returns the PIX records with a transaction date on a day, and the records the transformer rejects
+// the duplicated records found are left out and collected by duplicates
+func (p *Pix) GetDBDay(repo port.Repository, day time.Time, duplicates *PixDuplicates) (map[string]port.Report, []PixRejection, error) {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	return pixRead(pixRange(repo, start, start), duplicates)
+}
+
+// pixRead reads the PIX records of a restricted repository keyed as on the files, with the records rejected
+func pixRead(repo port.Repository, duplicates *PixDuplicates) (map[string]port.Report, []PixRejection, error) {
+	var records []*Pix
+	if err := repo.Select(pixColumns).FindAll(&records, 0, 0, pixOrder); err != nil {
+		return nil, nil, err
+	}
+	result, rejected := pixRecords(records, duplicates)
+	return result, rejected, nil
+}
+
+// PixRejection is a database record the transformer rejects, by a collision or a short source NSU
+type PixRejection struct {
+	Record *Pix
+	Err    error
+}
+
+// pixRecords keys the database records by date and NSU as they are written on the files
+// records flagged as duplicated, and those the transformer rejects, are left out as on generation;
+// the rejected ones are returned, the duplicates collected by duplicates
+// the transformer derives a distinct NSU for every record of a day it keeps, so keys do not repeat
+func pixRecords(records []*Pix, duplicates *PixDuplicates) (map[string]port.Report, []PixRejection) {
+	transformer := NewPixTransformer(duplicates)
+	result := make(map[string]port.Report)
+	var rejected []PixRejection
+	for _, record := range records {
+		if record.Duplicated {
+			continue
+		}
+		if err := transformer.Transform(record); err != nil {
+			var dup *PixDuplicate
+			if !errors.As(err, &dup) {
+				rejected = append(rejected, PixRejection{Record: record, Err: err})
+			}
+			continue
+		}
+		result[record.GetKey()] = record
+	}
+	return result, rejected
+}
+
+// PixFileName returns the name of the PIX DIMP file of a transaction date
+func PixFileName(date time.Time) string {
+	return pixFilePrefix + date.Format(pixFileDate) + pixFileSuffix
+}
+
+// PixFileDate returns the transaction date of a PIX DIMP file from its name
+func PixFileDate(filename string) (time.Time, bool) {
+	name := filepath.Base(filename)
+	if !strings.HasPrefix(name, pixFilePrefix) || !strings.HasSuffix(name, pixFileSuffix) {
+		return time.Time{}, false
+	}
+	date, err := time.Parse(pixFileDate, strings.TrimSuffix(strings.TrimPrefix(name, pixFilePrefix), pixFileSuffix))
+	return date, err == nil
+}
+
+// pixParser reads the fields of a PIX DIMP line, keeping the first field error
+// PIX stays outside the Layout registry: its layouts are CADOC report versions in force by reference quarter,
+// with one record length per file, while a PIX DIMP file mixes header, transaction and trailer records of
+// different lengths, and writes dates, times and the MDR rate in forms the layout field types do not have
+type pixParser struct {
+	runes []rune
+	err   error
+}
+
+// newPixParser creates a new pixParser instance
+func newPixParser(line string) *pixParser {
+	return &pixParser{runes: []rune(line)}
+}
+
+// text returns the trimmed text of the positions start to end
+func (pp *pixParser) text(start int, end int) string {
+	return strings.TrimSpace(slice(pp.runes, start, end-start+1))
+}
+
+// time returns the date or time written on the positions start to end
+func (pp *pixParser) time(name string, start int, end int, layout string) time.Time {
+	s := pp.text(start, end)
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		pp.fail(name, start, end, fmt.Errorf("invalid date or time %q", s))
+	}
+	return t
+}
+
+// number returns the integer written on the positions start to end
+func (pp *pixParser) number(name string, start int, end int) int64 {
+	s := pp.text(start, end)
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		pp.fail(name, start, end, fmt.Errorf("invalid number %q", s))
+	}
+	return n
+}
+
+// fail keeps the first field error
+func (pp *pixParser) fail(name string, start int, end int, err error) {
+	if pp.err == nil {
+		pp.err = &FieldError{Field: name, Start: start, End: end, Err: err}
+	}
 }
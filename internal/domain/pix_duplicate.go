@@ -0,0 +1,105 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lavinas/cadoc6334/internal/port"
+)
+
+// DefaultPixDuplicateKey identifies a PIX transaction by its date and NSU
+var DefaultPixDuplicateKey = []string{"DataTransacao", "NSU"}
+
+// PixDuplicate reports a PIX record with the same key as an earlier record of the same day
+// Source and Original are the source NSUs of the duplicate and of the record kept; RowID is the row of the duplicate
+type PixDuplicate struct {
+	Day      time.Time
+	Key      string
+	Source   string
+	Original string
+	RowID    string
+}
+
+// Error returns the description of the duplicate
+func (d *PixDuplicate) Error() string {
+	return fmt.Sprintf("source NSU %s duplicates source NSU %s on %s with key %s",
+		d.Source, d.Original, d.Day.Format(pixFileDate), d.Key)
+}
+
+// Flag sets the duplicated column of the duplicate row, identified by its row id so that the record kept,
+// even with the same source NSU, is left as it is
+func (d *PixDuplicate) Flag(repo port.Repository) error {
+	if d.RowID == "" {
+		return fmt.Errorf("source NSU %s on %s: no row id, not flagged", d.Source, d.Day.Format(pixFileDate))
+	}
+	return repo.Update(&Pix{}, "duplicated", true, "ctid = ?::tid", d.RowID)
+}
+
+// PixLeftOut holds the database records left out of a PIX read, as duplicates or rejected by the transformer
+type PixLeftOut struct {
+	Duplicates []*PixDuplicate
+	Rejected   []PixRejection
+}
+
+// PixDuplicates detects duplicated PIX records by the values of the key fields
+// keys are compared within each day, so records must come ordered by transaction date
+type PixDuplicates struct {
+	fields []string
+	day    time.Time
+	seen   map[string]string
+	Found  []*PixDuplicate
+}
+
+// NewPixDuplicates creates a new PixDuplicates instance keyed on PIX record fields
+// field names are the ones listed by GetFields; empty uses DefaultPixDuplicateKey
+func NewPixDuplicates(fields []string) (*PixDuplicates, error) {
+	if len(fields) == 0 {
+		fields = DefaultPixDuplicateKey
+	}
+	known := make(map[string]bool)
+	for _, f := range NewPix().GetFields() {
+		known[f.Name] = true
+	}
+	for _, name := range fields {
+		if !known[name] {
+			return nil, fmt.Errorf("invalid PIX duplicate key field %q", name)
+		}
+	}
+	return &PixDuplicates{fields: fields, seen: make(map[string]string)}, nil
+}
+
+// Check reports the record as a *PixDuplicate when an earlier record of the day has the same key
+// the record is kept as the original otherwise
+func (d *PixDuplicates) Check(p *Pix) error {
+	if !p.DataTransacao.Equal(d.day) {
+		d.day = p.DataTransacao
+		d.seen = make(map[string]string)
+	}
+	key := d.key(p)
+	if original, ok := d.seen[key]; ok {
+		return d.add(p, key, original)
+	}
+	d.seen[key] = p.NSUSource
+	return nil
+}
+
+// add records a duplicate of the original source NSU and returns it
+func (d *PixDuplicates) add(p *Pix, key string, original string) *PixDuplicate {
+	dup := &PixDuplicate{Day: p.DataTransacao, Key: key, Source: p.NSUSource, Original: original, RowID: p.RowID}
+	d.Found = append(d.Found, dup)
+	return dup
+}
+
+// key returns the values of the key fields of a record
+func (d *PixDuplicates) key(p *Pix) string {
+	values := make(map[string]string)
+	for _, f := range p.GetFields() {
+		values[f.Name] = f.Value
+	}
+	parts := make([]string, len(d.fields))
+	for i, name := range d.fields {
+		parts[i] = values[name]
+	}
+	return strings.Join(parts, "|")
+}
@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// pixFormatter writes the fields of a PIX DIMP record, normalizing each one to its positions
+// text is left aligned and space padded, numbers are right aligned and zero filled
+// values that cannot be normalized are written whole and the first one is kept as a *FieldError
+// it is the writing side of pixParser and stays outside the Layout registry for the same reasons:
+// PIX records are not report versions, mix record lengths and have date, time and rate forms of their own
+type pixFormatter struct {
+	sb  strings.Builder
+	err error
+}
+
+// newPixFormatter creates a new pixFormatter instance
+func newPixFormatter() *pixFormatter {
+	return &pixFormatter{}
+}
+
+// text writes a text on the positions start to end
+// wider text is rejected or truncated by the overflow policy in force
+func (pf *pixFormatter) text(name string, value string, start int, end int) {
+	width := end - start + 1
+	value = strings.TrimRight(value, " ")
+	if runes := []rune(value); len(runes) > width {
+		if overflow != OverflowTruncate {
+			pf.fail(name, start, end, fmt.Errorf("value %q does not fit %d positions", value, width))
+		} else {
+			value = string(runes[:width])
+		}
+	}
+	pf.sb.WriteString(fmt.Sprintf("%-*s", width, value))
+}
+
+// cut writes a required text on the positions start to end, always keeping its leftmost characters
+func (pf *pixFormatter) cut(name string, value string, start int, end int) {
+	width := end - start + 1
+	value = strings.TrimSpace(value)
+	if value == "" {
+		pf.fail(name, start, end, fmt.Errorf("value is empty"))
+	}
+	if runes := []rune(value); len(runes) > width {
+		value = string(runes[:width])
+	}
+	pf.sb.WriteString(fmt.Sprintf("%-*s", width, value))
+}
+
+// number writes a non-negative integer on the positions start to end
+func (pf *pixFormatter) number(name string, value int64, start int, end int) {
+	width := end - start + 1
+	s := fmt.Sprintf("%0*d", width, value)
+	switch {
+	case value < 0:
+		pf.fail(name, start, end, fmt.Errorf("negative value %d", value))
+	case len(s) > width:
+		pf.fail(name, start, end, fmt.Errorf("value %s does not fit %d positions", s, width))
+	}
+	pf.sb.WriteString(s)
+}
+
+// amount writes a value with 2 implied decimals on the positions start to end
+func (pf *pixFormatter) amount(name string, value decimal.Decimal, start int, end int) {
+	pf.number(name, toImplied(value, 2), start, end)
+}
+
+// rate writes the MDR rate on the positions start to end in the form in force
+// the implied form is rounded with the mode in force; the text form is the database value zero filled on the left
+func (pf *pixFormatter) rate(name string, value string, start int, end int) {
+	setting := GetPixRate()
+	d, err := setting.Value(value)
+	if err != nil {
+		pf.fail(name, start, end, err)
+	}
+	if setting.Form == PixRateImplied {
+		pf.number(name, toImplied(d, pixRateDecimals), start, end)
+		return
+	}
+	width := end - start + 1
+	value = strings.TrimSpace(value)
+	switch {
+	case d.IsNegative():
+		pf.fail(name, start, end, fmt.Errorf("negative value %s", value))
+	case len(value) > width:
+		pf.fail(name, start, end, fmt.Errorf("value %q does not fit %d positions", value, width))
+	case len(value) < width:
+		value = strings.Repeat("0", width-len(value)) + value
+	}
+	pf.sb.WriteString(value)
+}
+
+// time writes a date or time with the layout of its positions
+func (pf *pixFormatter) time(value time.Time, layout string) {
+	pf.sb.WriteString(value.Format(layout))
+}
+
+// line returns the record written so far
+func (pf *pixFormatter) line() string {
+	return pf.sb.String()
+}
+
+// fail keeps the first field error
+func (pf *pixFormatter) fail(name string, start int, end int, err error) {
+	if pf.err == nil {
+		pf.err = &FieldError{Field: name, Start: start, End: end, Err: err}
+	}
+}
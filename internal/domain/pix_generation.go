@@ -0,0 +1,107 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/lavinas/cadoc6334/internal/port"
+)
+
+// PixGeneration records a generated PIX DIMP daily file on the generation-control table
+// SourceRecords and SourceDigest are the state of the database rows of the day the file was generated from;
+// incremental runs skip the days whose rows are still in that state and whose file still has the Checksum
+type PixGeneration struct {
+	DataTransacao time.Time `gorm:"column:datatransacao;primaryKey;type:date"`
+	FileName      string    `gorm:"column:file_name"`
+	Records       int64     `gorm:"column:records"`
+	Checksum      string    `gorm:"column:checksum"`
+	SourceRecords int64     `gorm:"column:source_records"`
+	SourceDigest  string    `gorm:"column:source_digest"`
+	GeneratedAt   time.Time `gorm:"column:generated_at"`
+}
+
+// NewPixGeneration creates a new PixGeneration instance for a file generated now from the source rows of the day
+func NewPixGeneration(day time.Time, fileName string, records int64, checksum string, source *PixSource) *PixGeneration {
+	return &PixGeneration{
+		DataTransacao: day,
+		FileName:      fileName,
+		Records:       records,
+		Checksum:      checksum,
+		SourceRecords: source.Records,
+		SourceDigest:  source.Digest,
+		GeneratedAt:   time.Now(),
+	}
+}
+
+// TableName returns the generation-control table name
+func (g *PixGeneration) TableName() string {
+	return "pix_generation"
+}
+
+// GetKey returns the transaction date of the generated file
+func (g *PixGeneration) GetKey() string {
+	return g.DataTransacao.Format(pixFileDate)
+}
+
+// Save inserts or updates the generation of the day on the control table
+func (g *PixGeneration) Save(repo port.Repository) error {
+	return repo.Save(g)
+}
+
+// Current reports whether the file was generated from the source rows of the day as they are now
+func (g *PixGeneration) Current(source *PixSource) bool {
+	return g.SourceRecords == source.Records && g.SourceDigest == source.Digest
+}
+
+// GetPixGenerations returns the generated days between from and to, both inclusive, by transaction date
+// a zero from or to leaves the range open on that side
+func GetPixGenerations(repo port.Repository, from time.Time, to time.Time) (map[string]*PixGeneration, error) {
+	var records []*PixGeneration
+	if err := pixRange(repo, from, to).FindAll(&records, 0, 0, "datatransacao"); err != nil {
+		return nil, err
+	}
+	ret := make(map[string]*PixGeneration, len(records))
+	for _, record := range records {
+		ret[record.GetKey()] = record
+	}
+	return ret, nil
+}
+
+// PixSource is the state of the database rows of a transaction date, read from the pix_source view
+// Digest is the MD5 of the text of every row of the day, so any insert, update or delete changes it
+type PixSource struct {
+	DataTransacao time.Time `gorm:"column:datatransacao;type:date"`
+	Records       int64     `gorm:"column:records"`
+	Digest        string    `gorm:"column:digest"`
+}
+
+// TableName returns the source state view name
+func (s *PixSource) TableName() string {
+	return "pix_source"
+}
+
+// GetKey returns the transaction date of the source rows
+func (s *PixSource) GetKey() string {
+	return s.DataTransacao.Format(pixFileDate)
+}
+
+// GetPixSources returns the state of the source rows of every day between from and to, both inclusive,
+// ordered by transaction date; a zero from or to leaves the range open on that side
+func GetPixSources(repo port.Repository, from time.Time, to time.Time) ([]*PixSource, error) {
+	var records []*PixSource
+	if err := pixRange(repo, from, to).FindAll(&records, 0, 0, "datatransacao"); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// pixRange restricts a repository to the transaction dates between from and to, both inclusive
+// the range is half-open on the day after to, as GetDBDay, so the whole last day is taken
+func pixRange(repo port.Repository, from time.Time, to time.Time) port.Repository {
+	if !from.IsZero() {
+		repo = repo.Where("datatransacao >= ?", from)
+	}
+	if !to.IsZero() {
+		repo = repo.Where("datatransacao < ?", to.AddDate(0, 0, 1))
+	}
+	return repo
+}
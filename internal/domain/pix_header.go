@@ -5,16 +5,22 @@ import (
 	"time"
 )
 
+const (
+	// pixHeaderType and pixHeaderLength identify the header record of the PIX DIMP files
+	pixHeaderType   = "H"
+	pixHeaderLength = 11
+)
+
 // PixHeader represents the header record for the PIX report
 type PixHeader struct {
-	RecordType    string    `fixed:"1,1"`
-	DataTransacao time.Time `fixed:"2,11"`
+	RecordType    string
+	DataTransacao time.Time
 }
 
 // NewPixHeader creates a new PixHeader instance
 func NewPixHeader(date time.Time) *PixHeader {
 	return &PixHeader{
-		RecordType:    "H",
+		RecordType:    pixHeaderType,
 		DataTransacao: date,
 	}
 }
@@ -23,6 +29,28 @@ func NewPixHeader(date time.Time) *PixHeader {
 func (ph *PixHeader) Format() string {
 	ret := ""
 	ret += fmt.Sprintf("%-1s", ph.RecordType)
-	ret += ph.DataTransacao.Format("2006/01/02")
+	ret += ph.DataTransacao.Format(pixDate)
 	return ret
 }
+
+// Parse parses the header record of a PIX DIMP file
+func (ph *PixHeader) Parse(line string) error {
+	pp := newPixParser(line)
+	if n := len(pp.runes); n != pixHeaderLength {
+		return fmt.Errorf("PIX header has %d positions, expected %d", n, pixHeaderLength)
+	}
+	if ph.RecordType = pp.text(1, 1); ph.RecordType != pixHeaderType {
+		return fmt.Errorf("invalid PIX header record type %q, expected %s", ph.RecordType, pixHeaderType)
+	}
+	ph.DataTransacao = pp.time("DataTransacao", 2, 11, pixDate)
+	return pp.err
+}
+
+// Check checks that a transaction record belongs to the date of the file
+func (ph *PixHeader) Check(p *Pix) error {
+	if !p.DataTransacao.Equal(ph.DataTransacao) {
+		return fmt.Errorf("transaction date %s differs from the header date %s",
+			p.DataTransacao.Format(pixFileDate), ph.DataTransacao.Format(pixFileDate))
+	}
+	return nil
+}
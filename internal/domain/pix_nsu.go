@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// pixSourceNSUMin is the shortest source NSU holding every character the target NSU is derived from
+const pixSourceNSUMin = 23
+
+// PixCollisionError reports two source NSUs of the same day deriving the same target NSU
+type PixCollisionError struct {
+	Day      time.Time
+	Target   string
+	Source   string
+	Previous string
+}
+
+// Error returns the description of the collision
+func (e *PixCollisionError) Error() string {
+	return fmt.Sprintf("NSU %s of source NSU %s collides with source NSU %s on %s",
+		e.Target, e.Source, e.Previous, e.Day.Format(pixFileDate))
+}
+
+// DerivePixNSU derives the NSU and the authorization code written on the PIX DIMP files from the source NSU
+// the NSU is "P" followed by the characters 5-10 and 15-23 and the last 4 characters of the source NSU;
+// the authorization code is its last 6 characters
+func DerivePixNSU(source string) (string, string, error) {
+	runes := []rune(source)
+	if len(runes) < pixSourceNSUMin {
+		return "", "", fmt.Errorf("source NSU %q has %d characters, expected at least %d", source, len(runes), pixSourceNSUMin)
+	}
+	nsu := "P" + string(runes[4:10]) + string(runes[14:23]) + string(runes[len(runes)-4:])
+	auth := string(runes[len(runes)-6:])
+	return nsu, auth, nil
+}
+
+// PixTransformer derives the NSU and authorization code of the PIX records before they are formatted,
+// detects duplicated records and checks that no two source NSUs of the same day derive the same NSU
+// records must come ordered by transaction date, as the NSUs of a day are forgotten when the next starts
+type PixTransformer struct {
+	duplicates *PixDuplicates
+	day        time.Time
+	targets    map[string]string
+}
+
+// NewPixTransformer creates a new PixTransformer instance
+// duplicates detects the duplicated records by key; with nil only repeated source NSUs are reported, uncollected
+func NewPixTransformer(duplicates *PixDuplicates) *PixTransformer {
+	return &PixTransformer{duplicates: duplicates, targets: make(map[string]string)}
+}
+
+// Transform sets NSU and CodigoAutorizacao of the record from its source NSU
+// a record whose NSU was derived from another source NSU earlier that day is reported as a *PixCollisionError;
+// a duplicate of an earlier record of the same day, by key or by source NSU, as a *PixDuplicate
+func (t *PixTransformer) Transform(p *Pix) error {
+	if !p.DataTransacao.Equal(t.day) {
+		t.day = p.DataTransacao
+		t.targets = make(map[string]string)
+	}
+	nsu, auth, err := DerivePixNSU(p.NSUSource)
+	if err != nil {
+		return err
+	}
+	p.NSU, p.CodigoAutorizacao = nsu, auth
+	previous, taken := t.targets[nsu]
+	if taken && previous != p.NSUSource {
+		return &PixCollisionError{Day: t.day, Target: nsu, Source: p.NSUSource, Previous: previous}
+	}
+	if t.duplicates == nil {
+		if taken {
+			return &PixDuplicate{Day: t.day, Key: nsu, Source: p.NSUSource, Original: previous, RowID: p.RowID}
+		}
+	} else {
+		if err := t.duplicates.Check(p); err != nil {
+			return err
+		}
+		if taken {
+			return t.duplicates.add(p, nsu, previous)
+		}
+	}
+	t.targets[nsu] = p.NSUSource
+	return nil
+}
@@ -0,0 +1,175 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// sqlPixNSU is the derivation of the former SQL script:
+// concat('P', substr(nsu, 5, 6), substr(nsu, 15, 9), RIGHT(nsu, 4)) and right(nsu, 6)
+func sqlPixNSU(source string) (string, string) {
+	runes := []rune(source)
+	substr := func(start, length int) string {
+		start--
+		end := start + length
+		if start > len(runes) {
+			return ""
+		}
+		if end > len(runes) {
+			end = len(runes)
+		}
+		return string(runes[start:end])
+	}
+	right := func(n int) string {
+		if n > len(runes) {
+			n = len(runes)
+		}
+		return string(runes[len(runes)-n:])
+	}
+	return "P" + substr(5, 6) + substr(15, 9) + right(4), right(6)
+}
+
+func TestDerivePixNSU(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		nsu     string
+		auth    string
+		wantErr bool
+	}{
+		{name: "empty", source: "", wantErr: true},
+		{name: "22 characters", source: "ABCD123456EFGH12345678", wantErr: true},
+		{name: "22 multibyte characters", source: "ÁBCD123456ÉFGH1234567Ç", wantErr: true},
+		{name: "23 characters", source: "ABCD123456EFGH123456789", nsu: "P1234561234567896789", auth: "456789"},
+		{name: "27 characters", source: "ABCD123456EFGH123456789WXYZ", nsu: "P123456123456789WXYZ", auth: "89WXYZ"},
+		{name: "multibyte", source: "ÁBCD12345ÇÉFGH12345678ÕWXYZ", nsu: "P12345Ç12345678ÕWXYZ", auth: "8ÕWXYZ"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nsu, auth, err := DerivePixNSU(tt.source)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("DerivePixNSU(%q) = %q, %q, expected an error", tt.source, nsu, auth)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DerivePixNSU(%q) error: %v", tt.source, err)
+			}
+			if nsu != tt.nsu || auth != tt.auth {
+				t.Errorf("DerivePixNSU(%q) = %q, %q, expected %q, %q", tt.source, nsu, auth, tt.nsu, tt.auth)
+			}
+			if n := len([]rune(nsu)); n != 20 {
+				t.Errorf("DerivePixNSU(%q) NSU has %d characters, expected 20", tt.source, n)
+			}
+		})
+	}
+}
+
+func TestDerivePixNSUMatchesSQL(t *testing.T) {
+	sources := []string{
+		"ABCD123456EFGH123456789",
+		"ABCD123456EFGH123456789WXYZ",
+		"E18236120202507011234s0123456789ab",
+		"ÁBCD12345ÇÉFGH12345678ÕWXYZ",
+		"0000000000000000000000000000000000000000000000000",
+	}
+	for _, source := range sources {
+		nsu, auth, err := DerivePixNSU(source)
+		if err != nil {
+			t.Fatalf("DerivePixNSU(%q) error: %v", source, err)
+		}
+		sqlNSU, sqlAuth := sqlPixNSU(source)
+		if nsu != sqlNSU || auth != sqlAuth {
+			t.Errorf("DerivePixNSU(%q) = %q, %q, SQL derives %q, %q", source, nsu, auth, sqlNSU, sqlAuth)
+		}
+	}
+}
+
+func TestPixTransformer(t *testing.T) {
+	day := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	next := day.AddDate(0, 0, 1)
+	// both sources derive P123456123456789WXYZ
+	source := "ABCD123456EFGH123456789WXYZ"
+	colliding := "ZZZZ123456YYYY123456789WXYZ"
+	tests := []struct {
+		name       string
+		noDetector bool
+		records    []*Pix
+		want       []string
+	}{
+		{
+			name:    "same day collision",
+			records: []*Pix{{DataTransacao: day, NSUSource: source}, {DataTransacao: day, NSUSource: colliding}},
+			want:    []string{"", "collision"},
+		},
+		{
+			name:    "day reset",
+			records: []*Pix{{DataTransacao: day, NSUSource: source}, {DataTransacao: next, NSUSource: colliding}},
+			want:    []string{"", ""},
+		},
+		{
+			name:    "same source repeat",
+			records: []*Pix{{DataTransacao: day, NSUSource: source}, {DataTransacao: day, NSUSource: source}},
+			want:    []string{"", "duplicate"},
+		},
+		{
+			name:       "same source repeat without detector",
+			noDetector: true,
+			records:    []*Pix{{DataTransacao: day, NSUSource: source}, {DataTransacao: day, NSUSource: source}},
+			want:       []string{"", "duplicate"},
+		},
+		{
+			name:       "collision without detector",
+			noDetector: true,
+			records:    []*Pix{{DataTransacao: day, NSUSource: source}, {DataTransacao: day, NSUSource: colliding}},
+			want:       []string{"", "collision"},
+		},
+		{
+			name:    "short source",
+			records: []*Pix{{DataTransacao: day, NSUSource: "ABCD"}},
+			want:    []string{"error"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			duplicates, err := NewPixDuplicates(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.noDetector {
+				duplicates = nil
+			}
+			transformer := NewPixTransformer(duplicates)
+			for i, record := range tt.records {
+				err := transformer.Transform(record)
+				var collision *PixCollisionError
+				var dup *PixDuplicate
+				got := ""
+				switch {
+				case err == nil:
+				case errors.As(err, &collision):
+					got = "collision"
+				case errors.As(err, &dup):
+					got = "duplicate"
+				default:
+					got = "error"
+				}
+				if got != tt.want[i] {
+					t.Errorf("record %d: Transform() = %v, expected %q", i, err, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPixTransformerDerives(t *testing.T) {
+	record := &Pix{DataTransacao: time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), NSUSource: "ABCD123456EFGH123456789WXYZ"}
+	if err := NewPixTransformer(nil).Transform(record); err != nil {
+		t.Fatal(err)
+	}
+	if record.NSU != "P123456123456789WXYZ" || record.CodigoAutorizacao != "89WXYZ" {
+		t.Errorf("Transform() set NSU %q and authorization %q", record.NSU, record.CodigoAutorizacao)
+	}
+}
@@ -0,0 +1,105 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// pixRateDecimals is the decimal scale of the MDR rate written in the implied form, e.g. 0.99% is written 00099
+const pixRateDecimals = 2
+
+// PixRateForm represents how the MDR rate is written on the PIX DIMP files
+type PixRateForm string
+
+const (
+	// PixRateText writes the database value as it is, zero filled on the left, e.g. "0.99" as 00.99
+	PixRateText PixRateForm = "text"
+	// PixRateImplied writes the rate as a number with 2 implied decimals, e.g. 0.99% as 00099
+	PixRateImplied PixRateForm = "implied"
+)
+
+// PixRate is the form of the MDR rate on the files and the scale of digits-only database values
+// Scale is the number of implied decimals of a database value without decimal separator:
+// with scale 2 "0099" is 0.99%, with scale 0 "1" is 1%; values with a separator are always percentages
+type PixRate struct {
+	Form  PixRateForm
+	Scale int32
+}
+
+// DefaultPixRate writes the text form, as the files always were, and reads digits-only database values
+// with 2 implied decimals
+var DefaultPixRate = PixRate{Form: PixRateText, Scale: 2}
+
+// pixRateMaxScale is the largest scale accepted for digits-only database values
+const pixRateMaxScale = 6
+
+// pixRateSetting is the MDR rate setting in force
+var pixRateSetting = DefaultPixRate
+
+// NewPixRate creates a new PixRate instance from a form name and a scale
+// an empty form means text and a nil scale means 2
+func NewPixRate(form string, scale *int32) (PixRate, error) {
+	rate := DefaultPixRate
+	switch PixRateForm(form) {
+	case "", PixRateText:
+	case PixRateImplied:
+		rate.Form = PixRateImplied
+	default:
+		return PixRate{}, fmt.Errorf("invalid PIX rate form %q: expected text or implied", form)
+	}
+	if scale != nil {
+		if *scale < 0 || *scale > pixRateMaxScale {
+			return PixRate{}, fmt.Errorf("invalid PIX rate scale %d: expected 0 to %d", *scale, pixRateMaxScale)
+		}
+		rate.Scale = *scale
+	}
+	return rate, nil
+}
+
+// SetPixRate sets the MDR rate setting used when writing, reading and comparing PIX records
+func SetPixRate(rate PixRate) {
+	pixRateSetting = rate
+}
+
+// GetPixRate returns the MDR rate setting in force
+func GetPixRate() PixRate {
+	return pixRateSetting
+}
+
+// Value returns the percentage of a database rate
+// a value with a decimal separator, "." or ",", is the percentage itself; digits only have Scale implied
+// decimals; empty is zero
+func (r PixRate) Value(value string) (decimal.Decimal, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return decimal.Zero, nil
+	}
+	if strings.ContainsAny(value, ".,") {
+		d, err := decimal.NewFromString(strings.Replace(value, ",", ".", 1))
+		if err != nil {
+			return decimal.Zero, fmt.Errorf("invalid rate %q", value)
+		}
+		return d, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("invalid rate %q", value)
+	}
+	return fromImplied(n, r.Scale), nil
+}
+
+// parsed returns the rate read from a file as a database value
+// in the implied form the digits are always 2 implied decimals, so they are returned as a percentage
+func (r PixRate) parsed(text string) string {
+	if r.Form != PixRateImplied {
+		return text
+	}
+	n, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return text
+	}
+	return fromImplied(n, pixRateDecimals).StringFixed(pixRateDecimals)
+}
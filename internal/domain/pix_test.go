@@ -0,0 +1,119 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lavinas/cadoc6334/internal/port"
+)
+
+// keysetRepo serves PIX rows ordered and filtered as the database does for EachDBOrdered
+// row ids are compared as tids, (block,offset), and not as text
+type keysetRepo struct {
+	t       *testing.T
+	rows    []*Pix
+	queries int
+}
+
+// tid returns the block and offset of a row id
+func tid(s string) [2]int {
+	var block, offset int
+	fmt.Sscanf(s, "(%d,%d)", &block, &offset)
+	return [2]int{block, offset}
+}
+
+// less orders rows by transaction date, source NSU and row id
+func less(a *Pix, b *Pix) bool {
+	if !a.DataTransacao.Equal(b.DataTransacao) {
+		return a.DataTransacao.Before(b.DataTransacao)
+	}
+	if a.NSUSource != b.NSUSource {
+		return a.NSUSource < b.NSUSource
+	}
+	ta, tb := tid(a.RowID), tid(b.RowID)
+	return ta[0] < tb[0] || (ta[0] == tb[0] && ta[1] < tb[1])
+}
+
+func (r *keysetRepo) FindAll(dest interface{}, limit int, offset int, orderBy string, conditions ...interface{}) error {
+	r.queries++
+	if orderBy != pixOrder {
+		r.t.Fatalf("order %q, expected %q", orderBy, pixOrder)
+	}
+	rows := append([]*Pix(nil), r.rows...)
+	sort.Slice(rows, func(i, j int) bool { return less(rows[i], rows[j]) })
+	var after *Pix
+	if len(conditions) > 0 {
+		if q := conditions[0].(string); !strings.Contains(q, "ctid") {
+			r.t.Fatalf("keyset condition %q without the row id", q)
+		}
+		after = &Pix{DataTransacao: conditions[1].(time.Time), NSUSource: conditions[2].(string), RowID: conditions[3].(string)}
+	}
+	var ret []*Pix
+	for _, row := range rows {
+		if after != nil && !less(after, row) {
+			continue
+		}
+		if len(ret) == limit {
+			break
+		}
+		copied := *row
+		ret = append(ret, &copied)
+	}
+	*dest.(*[]*Pix) = ret
+	return nil
+}
+
+func (r *keysetRepo) FindByPrimaryKey(dest interface{}, keyName string, keyValue interface{}) error {
+	return nil
+}
+
+func (r *keysetRepo) Where(query interface{}, args ...interface{}) port.Repository { return r }
+
+func (r *keysetRepo) Select(columns string) port.Repository { return r }
+
+func (r *keysetRepo) Save(value interface{}) error { return nil }
+
+func (r *keysetRepo) Update(model interface{}, column string, value interface{}, conditions ...interface{}) error {
+	return nil
+}
+
+func TestEachDBOrderedTies(t *testing.T) {
+	day := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	source := "ABCD123456EFGH123456789WXYZ"
+	var rows []*Pix
+	// rows sharing date and source NSU, on row ids whose text order differs from their order
+	for _, id := range []string{"(10,1)", "(2,1)", "(2,12)", "(2,3)", "(1,7)"} {
+		rows = append(rows, &Pix{DataTransacao: day, NSUSource: source, RowID: id})
+	}
+	rows = append(rows,
+		&Pix{DataTransacao: day, NSUSource: "B" + source, RowID: "(0,1)"},
+		&Pix{DataTransacao: day.AddDate(0, 0, 1), NSUSource: source, RowID: "(0,2)"},
+		&Pix{DataTransacao: day, NSUSource: source, RowID: "(3,1)", Duplicated: true},
+	)
+	want := []string{"(1,7)", "(2,1)", "(2,3)", "(2,12)", "(3,1)", "(10,1)", "(0,1)", "(0,2)"}
+	for _, batch := range []int{1, 2, 3, 100} {
+		t.Run(fmt.Sprintf("batch %d", batch), func(t *testing.T) {
+			repo := &keysetRepo{t: t, rows: rows}
+			var got []string
+			err := NewPix().EachDBOrdered(repo, time.Time{}, time.Time{}, batch, func(record *Pix) error {
+				got = append(got, record.RowID)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var kept []string
+			for _, id := range want {
+				if id != "(3,1)" {
+					kept = append(kept, id)
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(kept) {
+				t.Errorf("rows read %v, expected %v", got, kept)
+			}
+		})
+	}
+}
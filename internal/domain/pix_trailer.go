@@ -2,26 +2,109 @@ package domain
 
 import (
 	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// pixTrailerType identifies the trailer record of the PIX DIMP files
+	pixTrailerType = "T"
+	// pixCountLength and pixTotalsLength are the lengths of the trailer layouts
+	pixCountLength  = 11
+	pixTotalsLength = 45
+)
+
+// PixTrailerLayout represents the content of the trailer of the PIX DIMP files
+type PixTrailerLayout string
+
+const (
+	// PixTrailerCount holds the number of records (positions 2-11)
+	PixTrailerCount PixTrailerLayout = "count"
+	// PixTrailerTotals adds the sums of ValorBrutoOriginal (12-28) and ValorMDROriginal (29-45),
+	// with 2 implied decimals
+	PixTrailerTotals PixTrailerLayout = "totals"
 )
 
+// ParsePixTrailerLayout parses a trailer layout name; empty means count
+func ParsePixTrailerLayout(s string) (PixTrailerLayout, error) {
+	switch PixTrailerLayout(s) {
+	case "", PixTrailerCount:
+		return PixTrailerCount, nil
+	case PixTrailerTotals:
+		return PixTrailerTotals, nil
+	}
+	return "", fmt.Errorf("invalid PIX trailer layout %q: expected count or totals", s)
+}
+
 // PixTrailer represents the trailer record for the PIX report
 type PixTrailer struct {
-	RecordType   string `fixed:"1,1" gorm:"column:record_type"`
-	TotalRecords int64  `fixed:"2,11" gorm:"column:total_records"`
+	RecordType      string
+	TotalRecords    int64
+	ValorBrutoTotal decimal.Decimal
+	ValorMDRTotal   decimal.Decimal
+	Layout          PixTrailerLayout
 }
 
-// NewPixTrailer creates a new PixTrailer instance
-func NewPixTrailer(totalRecords int64) *PixTrailer {
+// NewPixTrailer creates a new PixTrailer instance with no records
+func NewPixTrailer(layout PixTrailerLayout) *PixTrailer {
 	return &PixTrailer{
-		RecordType:   "T",
-		TotalRecords: totalRecords,
+		RecordType: pixTrailerType,
+		Layout:     layout,
 	}
 }
 
+// Add counts a record and adds its values, as written on the file, to the totals
+func (pt *PixTrailer) Add(p *Pix) {
+	pt.TotalRecords++
+	pt.ValorBrutoTotal = pt.ValorBrutoTotal.Add(fromImplied(toImplied(p.ValorBrutoOriginal, 2), 2))
+	pt.ValorMDRTotal = pt.ValorMDRTotal.Add(fromImplied(toImplied(p.ValorMDROriginal, 2), 2))
+}
+
 // Format marshals the PixTrailer struct into a fixed-width format.
 func (pt *PixTrailer) Format() string {
 	ret := ""
 	ret += fmt.Sprintf("%-1s", pt.RecordType)
 	ret += fmt.Sprintf("%010d", pt.TotalRecords)
+	if pt.Layout == PixTrailerTotals {
+		ret += fmt.Sprintf("%017d", toImplied(pt.ValorBrutoTotal, 2))
+		ret += fmt.Sprintf("%017d", toImplied(pt.ValorMDRTotal, 2))
+	}
 	return ret
 }
+
+// Parse parses the trailer record of a PIX DIMP file
+// the layout is detected from the length of the line
+func (pt *PixTrailer) Parse(line string) error {
+	pp := newPixParser(line)
+	switch len(pp.runes) {
+	case pixCountLength:
+		pt.Layout = PixTrailerCount
+	case pixTotalsLength:
+		pt.Layout = PixTrailerTotals
+		pt.ValorBrutoTotal = fromImplied(pp.number("ValorBrutoTotal", 12, 28), 2)
+		pt.ValorMDRTotal = fromImplied(pp.number("ValorMDRTotal", 29, 45), 2)
+	default:
+		return fmt.Errorf("PIX trailer has %d positions, expected %d or %d", len(pp.runes), pixCountLength, pixTotalsLength)
+	}
+	pt.RecordType = pp.text(1, 1)
+	pt.TotalRecords = pp.number("TotalRecords", 2, 11)
+	return pp.err
+}
+
+// Check checks the trailer against the trailer computed from the records of the file
+// totals are only checked when the trailer carries them
+func (pt *PixTrailer) Check(computed *PixTrailer) error {
+	if pt.TotalRecords != computed.TotalRecords {
+		return fmt.Errorf("trailer counts %d records, file has %d", pt.TotalRecords, computed.TotalRecords)
+	}
+	if pt.Layout != PixTrailerTotals {
+		return nil
+	}
+	if !pt.ValorBrutoTotal.Equal(computed.ValorBrutoTotal) {
+		return fmt.Errorf("trailer ValorBrutoOriginal total %s, records sum %s", pt.ValorBrutoTotal.StringFixed(2), computed.ValorBrutoTotal.StringFixed(2))
+	}
+	if !pt.ValorMDRTotal.Equal(computed.ValorMDRTotal) {
+		return fmt.Errorf("trailer ValorMDROriginal total %s, records sum %s", pt.ValorMDRTotal.StringFixed(2), computed.ValorMDRTotal.StringFixed(2))
+	}
+	return nil
+}
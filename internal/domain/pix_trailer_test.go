@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestPixTrailerAdd(t *testing.T) {
+	withRounding(t, RoundHalfUp)
+	amount := decimal.RequireFromString
+	records := []*Pix{
+		{ValorBrutoOriginal: amount("100.00"), ValorMDROriginal: amount("0.99")},
+		{ValorBrutoOriginal: amount("0.005"), ValorMDROriginal: amount("0.005")},
+		{ValorBrutoOriginal: amount("0.005"), ValorMDROriginal: amount("0.005")},
+		{ValorBrutoOriginal: amount("1234567890.12"), ValorMDROriginal: amount("12345.678")},
+	}
+	tests := []struct {
+		layout PixTrailerLayout
+		line   string
+	}{
+		{layout: PixTrailerCount, line: "T0000000004"},
+		{layout: PixTrailerTotals, line: "T0000000004" + "00000123456799014" + "00000000001234669"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.layout), func(t *testing.T) {
+			trailer := NewPixTrailer(tt.layout)
+			for _, r := range records {
+				trailer.Add(r)
+			}
+			// totals sum the values as written, each rounded to the cent: 0.005 is 0.01 twice, not 0.01 once
+			if want := amount("1234567990.14"); !trailer.ValorBrutoTotal.Equal(want) {
+				t.Errorf("ValorBrutoTotal = %s, expected %s", trailer.ValorBrutoTotal, want)
+			}
+			if want := amount("12346.69"); !trailer.ValorMDRTotal.Equal(want) {
+				t.Errorf("ValorMDRTotal = %s, expected %s", trailer.ValorMDRTotal, want)
+			}
+			line := trailer.Format()
+			if line != tt.line {
+				t.Fatalf("Format() = %q, expected %q", line, tt.line)
+			}
+			parsed := &PixTrailer{}
+			if err := parsed.Parse(line); err != nil {
+				t.Fatalf("Parse() error: %v", err)
+			}
+			if parsed.Layout != tt.layout {
+				t.Errorf("Parse() layout %s, expected %s", parsed.Layout, tt.layout)
+			}
+			if err := parsed.Check(trailer); err != nil {
+				t.Errorf("Check() error: %v", err)
+			}
+			trailer.Add(records[0])
+			if err := parsed.Check(trailer); err == nil {
+				t.Error("Check() accepted a trailer missing a record")
+			}
+		})
+	}
+}
@@ -1,33 +1,43 @@
 package domain
 
 import (
-	"bufio"
 	"fmt"
-	"os"
 	"strconv"
 
-	"github.com/ianlopshire/go-fixedwidth"
 	"github.com/lavinas/cadoc6334/internal/port"
-	"golang.org/x/text/encoding/charmap"
+	"github.com/shopspring/decimal"
 )
 
 // Ranking represents the ranking data model
 type Ranking struct {
-	Year         int64   `fixed:"1,4" gorm:"column:ano"`
-	Quarter      int64   `fixed:"5,5" gorm:"column:trimestre"`
-	ClientCode   string  `fixed:"6,13" gorm:"column:codigo_estabelecimento"`
-	Function     string  `fixed:"14,14" gorm:"column:funcao"`
-	Brand        int64   `fixed:"15,16" gorm:"column:bandeira"`
-	Capture      int64   `fixed:"17,17" gorm:"column:forma_captura"`
-	Installments int64   `fixed:"18,19" gorm:"column:numero_parcelas"`
-	Segment      int64   `fixed:"20,22" gorm:"column:codigo_segmento"`
-	Value        float64 `gorm:"column:valor_transacoes"`
-	ValueInt     int64   `fixed:"23,37"`
-	Qtty         int64   `fixed:"38,49" gorm:"column:quantidade_transacoes"`
-	Discount     float64 `gorm:"column:taxa_desconto_media"`
-	DiscountInt  int64   `fixed:"50,53"`
+	Year         int64           `gorm:"column:ano"`
+	Quarter      int64           `gorm:"column:trimestre"`
+	ClientCode   string          `gorm:"column:codigo_estabelecimento"`
+	Function     string          `gorm:"column:funcao"`
+	Brand        int64           `gorm:"column:bandeira"`
+	Capture      int64           `gorm:"column:forma_captura"`
+	Installments int64           `gorm:"column:numero_parcelas"`
+	Segment      int64           `gorm:"column:codigo_segmento"`
+	Value        decimal.Decimal `gorm:"column:valor_transacoes"`
+	Qtty         int64           `gorm:"column:quantidade_transacoes"`
+	Discount     decimal.Decimal `gorm:"column:taxa_desconto_media"`
 }
 
+// rankingLayout describes the RANKING.TXT record
+var rankingLayout = registerLayout("RANKING", &Ranking{},
+	numericAt("Year", 1, 4),
+	numericAt("Quarter", 5, 1).oneOf("1", "2", "3", "4"),
+	textAt("ClientCode", 6, 8),
+	textAt("Function", 14, 1),
+	numericAt("Brand", 15, 2),
+	numericAt("Capture", 17, 1),
+	numericAt("Installments", 18, 2),
+	numericAt("Segment", 20, 3),
+	decimalAt("Value", 23, 15, 2),
+	numericAt("Qtty", 38, 12),
+	decimalAt("Discount", 50, 4, 2),
+)
+
 // NewRanking creates a new Ranking instance
 func NewRanking() *Ranking {
 	return &Ranking{}
@@ -40,23 +50,7 @@ func (r *Ranking) GetName() string {
 
 // Format marshals the Ranking struct into a fixed-width format.
 func (r *Ranking) Format() string {
-	ret := ""
-	ret += fmt.Sprintf("%04d", r.Year)
-	ret += fmt.Sprintf("%01d", r.Quarter)
-	ret += fmt.Sprintf("%-8s", r.ClientCode)
-	ret += fmt.Sprintf("%01s", r.Function)
-	ret += fmt.Sprintf("%02d", r.Brand)
-	ret += fmt.Sprintf("%01d", r.Capture)
-	ret += fmt.Sprintf("%02d", r.Installments)
-	ret += fmt.Sprintf("%03d", r.Segment)
-	// Convert Value to int representation
-	r.ValueInt = int64(r.Value*100 + 0.5)
-	ret += fmt.Sprintf("%015d", r.ValueInt)
-	ret += fmt.Sprintf("%012d", r.Qtty)
-	// Convert Discount to int representation
-	r.DiscountInt = int64(r.Discount*100 + 0.5)
-	ret += fmt.Sprintf("%04d", r.DiscountInt)
-	return ret
+	return rankingLayout.Format(r)
 }
 
 // Validate validates the Ranking header information.
@@ -85,16 +79,16 @@ func (r *Ranking) Validate() error {
 	if r.Segment <= 0 {
 		return fmt.Errorf("invalid segment in header")
 	}
-	if r.Value < 0 {
+	if r.Value.IsNegative() {
 		return fmt.Errorf("invalid value in header")
 	}
 	if r.Qtty < 0 {
 		return fmt.Errorf("invalid quantity in header")
 	}
-	if r.Discount < 0 {
+	if r.Discount.IsNegative() {
 		return fmt.Errorf("invalid discount in header")
 	}
-	return nil
+	return rankingLayout.Validate(r)
 }
 
 // TableName returns the table name for the Ranking struct
@@ -108,14 +102,17 @@ func (r *Ranking) GetKey() string {
 }
 
 // FindAll retrieves all Ranking records.
-func (r *Ranking) GetDB(repo port.Repository) (map[string]port.Report, error) {
+func (r *Ranking) GetDB(repo port.Repository, period port.Period) (map[string]port.Report, error) {
 	var records []*Ranking
-	err := repo.FindAll(&records, 0, 0, "")
+	err := repo.FindAll(&records, 0, 0, "", "ano = ? AND trimestre = ?", period.Year, period.Quarter)
 	if err != nil {
 		return nil, err
 	}
 	ret := make(map[string]port.Report)
 	for _, rec := range records {
+		if err := period.Check(rec.Year, rec.Quarter); err != nil {
+			return nil, fmt.Errorf("key %s: %w", rec.GetKey(), err)
+		}
 		if cc, err := strconv.Atoi(rec.ClientCode); err == nil {
 			rec.ClientCode = fmt.Sprintf("%08d", cc)
 		}
@@ -126,64 +123,57 @@ func (r *Ranking) GetDB(repo port.Repository) (map[string]port.Report, error) {
 
 // ParseLine parses a line of text into a Ranking struct
 func (r *Ranking) Parse(line string) (*Ranking, error) {
-	err := fixedwidth.Unmarshal([]byte(line), r)
-	if err != nil {
+	if err := rankingLayout.Parse(line, r); err != nil {
 		return nil, err
 	}
-	// Convert ValueInt and DiscountInt back to float64
-	r.Value = float64(float64(r.ValueInt) / float64(100))
-	r.Discount = float64(float64(r.DiscountInt) / float64(100))
 	return r, nil
 }
 
 func (r *Ranking) String() string {
-	return fmt.Sprintf("Year: %d, Quarter: %d, ClientCode: %s, Function: %s, Brand: %d, Capture: %d, Installments: %d, Segment: %d, Value: %.2f, Qtty: %d, Discount: %.2f",
-		r.Year, r.Quarter, r.ClientCode, r.Function, r.Brand, r.Capture, r.Installments, r.Segment, r.Value, r.Qtty, r.Discount)
+	return fmt.Sprintf("Year: %d, Quarter: %d, ClientCode: %s, Function: %s, Brand: %d, Capture: %d, Installments: %d, Segment: %d, Value: %s, Qtty: %d, Discount: %s",
+		r.Year, r.Quarter, r.ClientCode, r.Function, r.Brand, r.Capture, r.Installments, r.Segment, r.Value.StringFixed(2), r.Qtty, r.Discount.StringFixed(2))
+}
+
+// GetFields returns the Ranking fields for field level comparison
+func (r *Ranking) GetFields() []port.Field {
+	return []port.Field{
+		intField("Year", r.Year),
+		intField("Quarter", r.Quarter),
+		textField("ClientCode", r.ClientCode),
+		textField("Function", r.Function),
+		intField("Brand", r.Brand),
+		intField("Capture", r.Capture),
+		intField("Installments", r.Installments),
+		intField("Segment", r.Segment),
+		amountField("Value", r.Value),
+		intField("Qtty", r.Qtty),
+		rateField("Discount", r.Discount),
+	}
 }
 
 // ParseRankingFile parses a file of rankings into a slice of Ranking structs
-func (r *Ranking) ParseRankingFile(filename string) ([]*Ranking, error) {
-	f, err := os.Open(filename)
+func (r *Ranking) ParseRankingFile(filename string, opts port.ParseOptions) ([]*Ranking, error) {
+	reader, err := openFile(filename, "RANKING", opts)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
-	decoder := charmap.ISO8859_1.NewDecoder()
-	decodedReader := decoder.Reader(f)
-	scanner := bufio.NewScanner(decodedReader)
-	// read header
-	if !scanner.Scan() {
-		return nil, fmt.Errorf("file is empty")
-	}
-	headerLine := scanner.Text()
-	header, err := (&RankingHeader{}).Parse(headerLine)
-	if err != nil {
-		return nil, fmt.Errorf("error parsing header: %w", err)
-	}
 	// read rankings
 	rankings := []*Ranking{}
-	var count int64 = 0
-	for scanner.Scan() {
-		line := scanner.Text()
-		ranking, err := (&Ranking{}).Parse(line)
-		if err != nil {
-			return nil, fmt.Errorf("error parsing line: %w", err)
+	for reader.Next() {
+		ranking := &Ranking{}
+		if reader.Parse(ranking) && reader.CheckPeriod(ranking.Year, ranking.Quarter) {
+			rankings = append(rankings, ranking)
 		}
-		rankings = append(rankings, ranking)
-		count++
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
 	}
-	if err := header.Validate("RANKING", count); err != nil {
+	if err := reader.Close(); err != nil {
 		return nil, err
 	}
 	return rankings, nil
 }
 
 // GetParsedFile retrieves and maps Ranking records from a file.
-func (r *Ranking) GetParsedFile(filename string) (map[string]port.Report, error) {
-	fileRankings, err := r.ParseRankingFile(filename)
+func (r *Ranking) GetParsedFile(filename string, opts port.ParseOptions) (map[string]port.Report, error) {
+	fileRankings, err := r.ParseRankingFile(filename, opts)
 	if err != nil {
 		return nil, err
 	}
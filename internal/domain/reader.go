@@ -0,0 +1,267 @@
+package domain
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/lavinas/cadoc6334/internal/port"
+)
+
+// DefaultMaxErrors is the number of line errors collected from a file when no limit is configured
+const DefaultMaxErrors = 100
+
+// snippetWidth is the number of characters of a line quoted on errors that do not point to a field
+const snippetWidth = 40
+
+// LineError reports a problem on a line of a parsed file
+// Start and End are the 1-based column range of Field; zero when the problem is about the whole line
+type LineError struct {
+	Line    int
+	Start   int
+	End     int
+	Field   string
+	Snippet string
+	Err     error
+}
+
+// Error returns the description of the line error
+func (e *LineError) Error() string {
+	ret := fmt.Sprintf("line %d", e.Line)
+	if e.Start > 0 {
+		ret += fmt.Sprintf(" positions %d-%d", e.Start, e.End)
+	}
+	if e.Field != "" {
+		ret += " " + e.Field
+	}
+	return fmt.Sprintf("%s: %v [%q]", ret, e.Err, e.Snippet)
+}
+
+// Unwrap returns the underlying error
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// ParseErrors collects the line errors of a file
+// Truncated tells that reading stopped on the error limit, so more errors may follow
+type ParseErrors struct {
+	File      string
+	Errors    []*LineError
+	Truncated bool
+}
+
+// Error returns the description of every line error, one per line
+func (e *ParseErrors) Error() string {
+	ret := fmt.Sprintf("%d error(s) on %s file", len(e.Errors), e.File)
+	if e.Truncated {
+		ret += fmt.Sprintf(", stopped after %d", len(e.Errors))
+	}
+	for _, le := range e.Errors {
+		ret += "\n" + le.Error()
+	}
+	return ret
+}
+
+// Unwrap returns the line errors
+func (e *ParseErrors) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, le := range e.Errors {
+		errs[i] = le
+	}
+	return errs
+}
+
+// fileReader streams the data lines of a CADOC file, collecting every line error up to a limit
+// lines of any length are read; the header is read and parsed when the file is opened
+type fileReader struct {
+	name      string
+	opts      port.ParseOptions
+	enc       Encoding
+	file      *os.File
+	reader    *bufio.Reader
+	layout    *fileLayout
+	header    *RankingHeader
+	headerRaw string
+	line      int
+	text      string
+	records   int64
+	crlf      int
+	lf        int
+	maxErrors int
+	errs      *ParseErrors
+	err       error
+}
+
+// openFile opens a CADOC file and reads its header
+// name is the report the file is expected to hold; header errors are of type *HeaderError
+func openFile(filename string, name string, opts port.ParseOptions) (*fileReader, error) {
+	fr, err := newFileReader(filename, name, opts)
+	if err != nil {
+		return nil, err
+	}
+	if !fr.readLine() {
+		fr.file.Close()
+		if fr.err != nil {
+			return nil, &HeaderError{File: name, Err: fr.err}
+		}
+		return nil, newHeaderError(name, "", "file is empty")
+	}
+	fr.headerRaw = fr.text
+	fr.header = &RankingHeader{}
+	if _, err := fr.header.Parse(fr.text, fr.enc); err != nil {
+		fr.file.Close()
+		if he, ok := err.(*HeaderError); ok {
+			he.File = name
+		}
+		return nil, err
+	}
+	return fr, nil
+}
+
+// newFileReader opens a file to be read line by line
+// the file is decoded with the charset of the options and its policy is applied to every parsed line
+func newFileReader(filename string, name string, opts port.ParseOptions) (*fileReader, error) {
+	enc, err := NewEncoding(opts.Charset, opts.Unrepresentable)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	fr := &fileReader{
+		name:      name,
+		opts:      opts,
+		enc:       enc,
+		file:      file,
+		reader:    bufio.NewReader(enc.NewReader(file)),
+		layout:    newFileLayout(name, opts, enc),
+		maxErrors: opts.MaxErrors,
+		errs:      &ParseErrors{File: name},
+	}
+	if fr.maxErrors <= 0 {
+		fr.maxErrors = DefaultMaxErrors
+	}
+	return fr, nil
+}
+
+// readLine reads the next line without its terminator
+// both LF and CRLF terminators are accepted, and the last line may have none
+func (fr *fileReader) readLine() bool {
+	text, err := fr.reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		fr.err = err
+		return false
+	}
+	if text == "" {
+		return false
+	}
+	fr.line++
+	switch {
+	case strings.HasSuffix(text, "\r\n"):
+		fr.crlf++
+	case strings.HasSuffix(text, "\n"):
+		fr.lf++
+	}
+	fr.text = strings.TrimSuffix(strings.TrimSuffix(text, "\n"), "\r")
+	return true
+}
+
+// Next advances to the next data line
+// it returns false at the end of the file, on a read error or when the error limit is reached
+func (fr *fileReader) Next() bool {
+	if len(fr.errs.Errors) >= fr.maxErrors {
+		fr.errs.Truncated = fr.readLine()
+		return false
+	}
+	if !fr.readLine() {
+		return false
+	}
+	fr.records++
+	return true
+}
+
+// Text returns the current line as read from the file
+func (fr *fileReader) Text() string {
+	return fr.text
+}
+
+// Parse reads the current line into a record with the layout of the file
+func (fr *fileReader) Parse(record interface{}) bool {
+	return fr.Apply(func(line string) error {
+		return fr.layout.Parse(line, record)
+	})
+}
+
+// Apply calls a parse function on the current line after applying the encoding policy,
+// recording the error when it fails
+func (fr *fileReader) Apply(parse func(line string) error) bool {
+	text, err := fr.enc.Apply(fr.text)
+	if err != nil {
+		fr.fail(err)
+		return false
+	}
+	return fr.Check(parse(text))
+}
+
+// CheckPeriod checks the period of the current record, recording the error when it is outside the reference period
+func (fr *fileReader) CheckPeriod(year int64, quarter int64) bool {
+	return fr.Check(fr.opts.Period.Check(year, quarter))
+}
+
+// Check records an error found on the current line; it reports whether there was none
+func (fr *fileReader) Check(err error) bool {
+	if err != nil {
+		fr.fail(err)
+		return false
+	}
+	return true
+}
+
+// Complete reports whether the whole file was read, so checks over every line can be made
+func (fr *fileReader) Complete() bool {
+	return fr.err == nil && !fr.errs.Truncated
+}
+
+// fail records an error on the current line
+// field errors point to their column range, other errors quote the start of the line
+func (fr *fileReader) fail(err error) {
+	le := &LineError{Line: fr.line, Err: err}
+	var fe *FieldError
+	if errors.As(err, &fe) {
+		le.Start, le.End, le.Field, le.Err = fe.Start, fe.End, fe.Field, fe.Err
+		le.Snippet = slice([]rune(fr.text), fe.Start, fe.End-fe.Start+1)
+	} else {
+		le.Snippet = slice([]rune(fr.text), 1, snippetWidth)
+	}
+	fr.errs.Errors = append(fr.errs.Errors, le)
+}
+
+// Close closes the file and checks the CADOC header, if any, against the data lines read
+// it returns the read error, or the collected line errors as *ParseErrors, or the header error
+// a file mixing LF and CRLF terminators is accepted with a warning
+func (fr *fileReader) Close() error {
+	fr.file.Close()
+	if fr.err != nil {
+		return fmt.Errorf("error reading %s file at line %d: %w", fr.name, fr.line+1, fr.err)
+	}
+	if fr.crlf > 0 && fr.lf > 0 && fr.opts.Warn != nil {
+		fr.opts.Warn("%s file mixes line terminators: %d CRLF and %d LF lines", fr.name, fr.crlf, fr.lf)
+	}
+	// the record count is only known when the whole file was read
+	if fr.header != nil && !fr.errs.Truncated {
+		if err := fr.header.Validate(fr.name, fr.opts, fr.records); err != nil {
+			if len(fr.errs.Errors) == 0 {
+				return err
+			}
+			fr.errs.Errors = append([]*LineError{{Line: 1, Err: err, Snippet: fr.headerRaw}}, fr.errs.Errors...)
+		}
+	}
+	if len(fr.errs.Errors) > 0 {
+		return fr.errs
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lavinas/cadoc6334/internal/port"
+)
+
+// writeTemp writes the lines to a temporary file, LF terminated, and returns its path
+func writeTemp(t *testing.T, lines ...string) string {
+	path := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readAll applies parse to every line of a file and returns the error of Close
+func readAll(t *testing.T, path string, opts port.ParseOptions, parse func(line string) error) (*fileReader, error) {
+	fr, err := newFileReader(path, "TEST", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for fr.Next() {
+		fr.Apply(parse)
+	}
+	return fr, fr.Close()
+}
+
+func TestFileReaderMaxErrors(t *testing.T) {
+	bad := func(line string) error { return fmt.Errorf("bad line") }
+	tests := []struct {
+		name      string
+		lines     int
+		maxErrors int
+		errors    int
+		truncated bool
+	}{
+		{name: "under the limit", lines: 3, maxErrors: 5, errors: 3},
+		{name: "at the limit", lines: 3, maxErrors: 3, errors: 3},
+		{name: "over the limit", lines: 10, maxErrors: 3, errors: 3, truncated: true},
+		{name: "default limit", lines: DefaultMaxErrors + 5, errors: DefaultMaxErrors, truncated: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := make([]string, tt.lines)
+			for i := range lines {
+				lines[i] = fmt.Sprintf("line %d", i+1)
+			}
+			fr, err := readAll(t, writeTemp(t, lines...), port.ParseOptions{MaxErrors: tt.maxErrors}, bad)
+			var pe *ParseErrors
+			if !errors.As(err, &pe) {
+				t.Fatalf("Close() = %v, expected *ParseErrors", err)
+			}
+			if len(pe.Errors) != tt.errors || pe.Truncated != tt.truncated {
+				t.Errorf("%d errors, truncated %v, expected %d, %v", len(pe.Errors), pe.Truncated, tt.errors, tt.truncated)
+			}
+			if fr.Complete() == tt.truncated {
+				t.Errorf("Complete() = %v with truncated %v", fr.Complete(), tt.truncated)
+			}
+		})
+	}
+}
+
+func TestFileReaderLongLine(t *testing.T) {
+	long := strings.Repeat("x", 200*1024)
+	var got []int
+	_, err := readAll(t, writeTemp(t, "short", long, "end"), port.ParseOptions{Charset: "utf-8"}, func(line string) error {
+		got = append(got, len(line))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if want := []int{5, len(long), 3}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("line lengths %v, expected %v", got, want)
+	}
+}
+
+func TestFileReaderLineError(t *testing.T) {
+	line := "0123456789" + strings.Repeat("abcdefghij", 5)
+	tests := []struct {
+		name    string
+		err     error
+		start   int
+		end     int
+		field   string
+		snippet string
+	}{
+		{
+			name:  "field error",
+			err:   &FieldError{Field: "Amount", Start: 4, End: 8, Err: fmt.Errorf("invalid number")},
+			start: 4, end: 8, field: "Amount", snippet: "34567",
+		},
+		{
+			name:  "position error",
+			err:   &FieldError{Start: 12, End: 12, Err: fmt.Errorf("not representable")},
+			start: 12, end: 12, snippet: "b",
+		},
+		{
+			name:    "line error",
+			err:     fmt.Errorf("record has 60 positions"),
+			snippet: line[:snippetWidth],
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readAll(t, writeTemp(t, "ok", line), port.ParseOptions{}, func(l string) error {
+				if l == line {
+					return tt.err
+				}
+				return nil
+			})
+			var pe *ParseErrors
+			if !errors.As(err, &pe) || len(pe.Errors) != 1 {
+				t.Fatalf("Close() = %v, expected one line error", err)
+			}
+			le := pe.Errors[0]
+			if le.Line != 2 || le.Start != tt.start || le.End != tt.end || le.Field != tt.field || le.Snippet != tt.snippet {
+				t.Errorf("line error %+v, expected line 2 positions %d-%d field %q snippet %q",
+					le, tt.start, tt.end, tt.field, tt.snippet)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package domain
+
+import (
+	"fmt"
+
+	"github.com/lavinas/cadoc6334/internal/port"
+)
+
+// DefaultDocument is the CADOC document generated and reconciled when none is configured
+const DefaultDocument = "6334"
+
+// DataSource tells where the records of a report come from
+type DataSource string
+
+const (
+	// SourceDatabase reads the records of the period from the database, scoped to the institution filters
+	SourceDatabase DataSource = "database"
+	// SourceReference reads reference data shared by every institution and period from the database
+	SourceReference DataSource = "reference"
+	// SourceInstitution builds the records from the institution configuration, falling back to the database
+	SourceInstitution DataSource = "institution"
+	// SourceRun builds the records from the run itself (institution and period)
+	SourceRun DataSource = "run"
+)
+
+// ReportSpec describes a file of a CADOC document
+// Build creates the records of institution and run sources; Header tells whether the file
+// starts with the header record; DependsOn lists the reports generated before this one,
+// which is not generated when any of them fails
+type ReportSpec struct {
+	Document  string
+	Name      string
+	File      string
+	Source    DataSource
+	Header    bool
+	DependsOn []string
+	New       func() port.Report
+	Build     func(institution *Institution, period port.Period) map[string]port.Report
+}
+
+// reports holds the registered report specs in registration order
+var reports []*ReportSpec
+
+// the files of the CADOC 6334 document
+func init() {
+	for _, spec := range []ReportSpec{
+		{Name: "RANKING", File: "RANKING.TXT", Source: SourceDatabase, Header: true, New: func() port.Report { return NewRanking() }},
+		{Name: "CONCCRED", File: "CONCCRED.TXT", Source: SourceDatabase, Header: true, New: func() port.Report { return NewConccred() }},
+		{Name: "INFRESTA", File: "INFRESTA.TXT", Source: SourceDatabase, Header: true, New: func() port.Report { return NewInfresta() }},
+		{Name: "INFRTERM", File: "INFRTERM.TXT", Source: SourceDatabase, Header: true, New: func() port.Report { return NewInfrterm() }},
+		{Name: "DESCONTO", File: "DESCONTO.TXT", Source: SourceDatabase, Header: true, New: func() port.Report { return NewDiscount() }},
+		{Name: "INTERCAM", File: "INTERCAM.TXT", Source: SourceDatabase, Header: true, New: func() port.Report { return NewIntercam() }},
+		{Name: "SEGMENTO", File: "SEGMENTO.TXT", Source: SourceReference, Header: true, New: func() port.Report { return NewSegment() }},
+		{Name: "LUCRCRED", File: "LUCRCRED.TXT", Source: SourceDatabase, Header: true, New: func() port.Report { return NewLucrCred() }},
+		{Name: "CONTATOS", File: "CONTATOS.TXT", Source: SourceInstitution, Header: true, New: func() port.Report { return NewContact() },
+			Build: func(institution *Institution, period port.Period) map[string]port.Report {
+				return institution.GetContacts(period)
+			}},
+		{Name: "DATABASE", File: "DATABASE.TXT", Source: SourceRun, Header: false, New: func() port.Report { return &Database{} },
+			Build: func(institution *Institution, period port.Period) map[string]port.Report {
+				return map[string]port.Report{"DATABASE": NewDatabase(institution.CNPJBase, period)}
+			},
+			DependsOn: []string{"RANKING", "CONCCRED", "INFRESTA", "INFRTERM", "DESCONTO", "INTERCAM", "SEGMENTO", "LUCRCRED", "CONTATOS"}},
+	} {
+		spec.Document = DefaultDocument
+		if err := RegisterReport(spec); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterReport registers a file of a CADOC document
+// dependencies must be registered before the report, so the registration order is a valid generation order
+func RegisterReport(spec ReportSpec) error {
+	if spec.Document == "" || spec.Name == "" || spec.File == "" || spec.New == nil {
+		return fmt.Errorf("invalid report %q: document, name, file and constructor are required", spec.Name)
+	}
+	if (spec.Source == SourceInstitution || spec.Source == SourceRun) && spec.Build == nil {
+		return fmt.Errorf("invalid report %s: %s source requires a builder", spec.Name, spec.Source)
+	}
+	for _, r := range reports {
+		if r.Document == spec.Document && r.Name == spec.Name {
+			return fmt.Errorf("report %s of document %s already registered", spec.Name, spec.Document)
+		}
+	}
+	for _, dep := range spec.DependsOn {
+		if _, err := GetReport(spec.Document, dep); err != nil {
+			return fmt.Errorf("report %s depends on %s: %w", spec.Name, dep, err)
+		}
+	}
+	reports = append(reports, &spec)
+	return nil
+}
+
+// GetReport returns a registered report of a document
+func GetReport(document string, name string) (*ReportSpec, error) {
+	for _, r := range reports {
+		if r.Document == document && r.Name == name {
+			return r, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown report %s of document %s", name, document)
+}
+
+// GetReports returns the registered reports of a document in generation order
+func GetReports(document string) []*ReportSpec {
+	var ret []*ReportSpec
+	for _, r := range reports {
+		if r.Document == document {
+			ret = append(ret, r)
+		}
+	}
+	return ret
+}
+
+// GetDocuments returns the documents with registered reports
+func GetDocuments() []string {
+	var ret []string
+	seen := make(map[string]bool)
+	for _, r := range reports {
+		if !seen[r.Document] {
+			seen[r.Document] = true
+			ret = append(ret, r.Document)
+		}
+	}
+	return ret
+}
+
+// Reconcilable reports whether the file holds records that can be checked against the database
+func (s *ReportSpec) Reconcilable() bool {
+	return s.Source != SourceRun
+}
+
+// GetData returns the records of the report for an institution and reference period
+// institution sources fall back to the database when the institution configures no records
+func (s *ReportSpec) GetData(repo port.Repository, institution *Institution, period port.Period) (map[string]port.Report, error) {
+	switch s.Source {
+	case SourceRun:
+		return s.Build(institution, period), nil
+	case SourceInstitution:
+		if records := s.Build(institution, period); len(records) > 0 {
+			return records, nil
+		}
+	case SourceReference:
+		return s.New().GetDB(repo, period)
+	}
+	return s.New().GetDB(institution.GetRepository(repo), period)
+}
@@ -1,23 +1,25 @@
 package domain
 
 import (
-	"bufio"
 	"fmt"
-	"os"
 
-	"github.com/ianlopshire/go-fixedwidth"
 	"github.com/lavinas/cadoc6334/internal/port"
-	"golang.org/x/text/encoding/charmap"
 )
 
 // Segment represents a segment of a path.
 type Segment struct {
-	Name        string `fixed:"1,50" gorm:"column:nome_segmento"`
-	Description string `fixed:"51,300" gorm:"column:descricao_segmento"`
-	CodeStr     string `fixed:"301,303"`
+	Name        string `gorm:"column:nome_segmento"`
+	Description string `gorm:"column:descricao_segmento"`
 	Code        int64  `gorm:"column:codigo_segmento"`
 }
 
+// segmentLayout describes the SEGMENTO.TXT record
+var segmentLayout = registerLayout("SEGMENTO", &Segment{},
+	textAt("Name", 1, 50),
+	textAt("Description", 51, 250),
+	numericAt("Code", 301, 3),
+)
+
 // NewSegment creates a new Segment instance
 func NewSegment() *Segment {
 	return &Segment{}
@@ -30,11 +32,7 @@ func (s *Segment) GetName() string {
 
 // Format marshals the Segment struct into a fixed-width format.
 func (s *Segment) Format() string {
-	ret := ""
-	ret += fmt.Sprintf("%-50s", s.Name)
-	ret += fmt.Sprintf("%-250s", s.Description)
-	ret += fmt.Sprintf("%03d", s.Code)
-	return ret
+	return segmentLayout.Format(s)
 }
 
 // Validate validates the Segment information.
@@ -48,7 +46,7 @@ func (s *Segment) Validate() error {
 	if s.Code <= 0 {
 		return fmt.Errorf("invalid code in segment")
 	}
-	return nil
+	return segmentLayout.Validate(s)
 }
 
 // TableName returns the table name for the Segment struct
@@ -62,7 +60,8 @@ func (s *Segment) GetKey() string {
 }
 
 // FindAll retrieves all Segment records.
-func (s *Segment) GetDB(repo port.Repository) (map[string]port.Report, error) {
+// segments are reference data shared by every period, so the period is not used
+func (s *Segment) GetDB(repo port.Repository, period port.Period) (map[string]port.Report, error) {
 	var records []*Segment
 	err := repo.FindAll(&records, 0, 0, "")
 	if err != nil {
@@ -77,23 +76,7 @@ func (s *Segment) GetDB(repo port.Repository) (map[string]port.Report, error) {
 
 // Parse parses a fixed-width string into a Segment struct
 func (s *Segment) Parse(line string) error {
-	line, err := RemoveAccents(line)
-	if err != nil {
-		return fmt.Errorf("error removing accents: %w", err)
-	}
-	err = fixedwidth.Unmarshal([]byte(line), s)
-	if err != nil {
-		return err
-	}
-	if s.CodeStr == "" {
-		s.Code = 0
-		return nil
-	}
-	_, err = fmt.Sscanf(s.CodeStr, "%d", &s.Code)
-	if err != nil {
-		return fmt.Errorf("error parsing Code: %w", err)
-	}
-	return nil
+	return segmentLayout.Parse(line, s)
 }
 
 // String returns a string representation of the Segment struct
@@ -101,51 +84,39 @@ func (s *Segment) String() string {
 	return fmt.Sprintf("Name: %s, Description: %s, Code: %d", s.Name, s.Description, s.Code)
 }
 
+// GetFields returns the Segment fields for field level comparison
+func (s *Segment) GetFields() []port.Field {
+	return []port.Field{
+		textField("Name", s.Name),
+		textField("Description", s.Description),
+		intField("Code", s.Code),
+	}
+}
+
 // ParseSegmentFile parses a file of segments into a slice of Segment structs
-func (s *Segment) ParseSegmentFile(filename string) ([]*Segment, error) {
-	f, err := os.Open(filename)
+func (s *Segment) ParseSegmentFile(filename string, opts port.ParseOptions) ([]*Segment, error) {
+	reader, err := openFile(filename, "SEGMENTO", opts)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
-	decoder := charmap.ISO8859_1.NewDecoder()
-	decodedReader := decoder.Reader(f)
-	scanner := bufio.NewScanner(decodedReader)
-	// read header
-	if !scanner.Scan() {
-		return nil, fmt.Errorf("file is empty")
-	}
-	headerLine := scanner.Text()
-	header := &RankingHeader{}
-	_, err = header.Parse(headerLine)
-	if err != nil {
-		return nil, fmt.Errorf("error parsing header: %w", err)
-	}
 	// read records
 	segments := []*Segment{}
-	count := 0
-	for scanner.Scan() {
-		line := scanner.Text()
+	for reader.Next() {
 		segment := &Segment{}
-		err := segment.Parse(line)
-		if err != nil {
-			return nil, fmt.Errorf("error parsing line: %w", err)
+		if reader.Parse(segment) {
+			segments = append(segments, segment)
 		}
-		segments = append(segments, segment)
-		count++
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
 	}
-	if err := header.Validate("SEGMENTO", int64(count)); err != nil {
+	if err := reader.Close(); err != nil {
 		return nil, err
 	}
 	return segments, nil
 }
 
 // GetParsedFile retrieves and maps Segment records from a file.
-func (s *Segment) GetParsedFile(filename string) (map[string]port.Report, error) {
-	fileSegments, err := s.ParseSegmentFile(filename)
+// segments are reference data shared by every period, so only the header is checked
+func (s *Segment) GetParsedFile(filename string, opts port.ParseOptions) (map[string]port.Report, error) {
+	fileSegments, err := s.ParseSegmentFile(filename, opts)
 	if err != nil {
 		return nil, err
 	}
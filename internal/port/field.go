@@ -0,0 +1,27 @@
+package port
+
+import "github.com/shopspring/decimal"
+
+// FieldKind represents the kind of value held by a report field
+type FieldKind int
+
+const (
+	FieldText FieldKind = iota
+	FieldInteger
+	FieldAmount
+	FieldRate
+)
+
+// Field represents a named value of a report record, used for field level comparison
+// Number holds the numeric value of integer, amount and rate fields
+type Field struct {
+	Name   string
+	Kind   FieldKind
+	Value  string
+	Number decimal.Decimal
+}
+
+// IsDecimal reports whether the field holds a monetary amount or a rate
+func (f Field) IsDecimal() bool {
+	return f.Kind == FieldAmount || f.Kind == FieldRate
+}
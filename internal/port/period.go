@@ -0,0 +1,88 @@
+package port
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var periodRegex = regexp.MustCompile(`^(\d{4})Q([1-4])$`)
+
+// Period represents a CADOC reference period (year and quarter)
+type Period struct {
+	Year    int64
+	Quarter int64
+}
+
+// NewPeriod creates a new Period instance
+func NewPeriod(year int64, quarter int64) (Period, error) {
+	if year <= 0 {
+		return Period{}, fmt.Errorf("invalid period year: %d", year)
+	}
+	if quarter < 1 || quarter > 4 {
+		return Period{}, fmt.Errorf("invalid period quarter: %d", quarter)
+	}
+	return Period{Year: year, Quarter: quarter}, nil
+}
+
+// ParsePeriod parses a period in the YYYYQn format, e.g. 2025Q3
+func ParsePeriod(s string) (Period, error) {
+	m := periodRegex.FindStringSubmatch(s)
+	if m == nil {
+		return Period{}, fmt.Errorf("invalid period %q: expected YYYYQn, e.g. 2025Q3", s)
+	}
+	year, _ := strconv.ParseInt(m[1], 10, 64)
+	quarter, _ := strconv.ParseInt(m[2], 10, 64)
+	return NewPeriod(year, quarter)
+}
+
+// String returns the period in the YYYYQn format
+func (p Period) String() string {
+	return fmt.Sprintf("%04dQ%d", p.Year, p.Quarter)
+}
+
+// IsZero reports whether the period is unset
+func (p Period) IsZero() bool {
+	return p.Year == 0 && p.Quarter == 0
+}
+
+// Contains reports whether a record year and quarter belong to the period
+func (p Period) Contains(year int64, quarter int64) bool {
+	return p.Year == year && p.Quarter == quarter
+}
+
+// Check returns an error when a record year and quarter do not belong to the period
+// an unset period accepts every record
+func (p Period) Check(year int64, quarter int64) error {
+	if p.IsZero() || p.Contains(year, quarter) {
+		return nil
+	}
+	return fmt.Errorf("record from period %04dQ%d outside reference period %s", year, quarter, p)
+}
+
+// BaseDate returns the year and last month of the quarter (YYYYMM)
+func (p Period) BaseDate() string {
+	return fmt.Sprintf("%04d%02d", p.Year, p.Quarter*3)
+}
+
+// Start returns the first day of the quarter
+func (p Period) Start() time.Time {
+	return time.Date(int(p.Year), time.Month((p.Quarter-1)*3+1), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// End returns the first day after the quarter
+func (p Period) End() time.Time {
+	return p.Start().AddDate(0, 3, 0)
+}
+
+// Compare returns -1, 0 or +1 as the period is before, equal to or after another period
+func (p Period) Compare(o Period) int {
+	switch {
+	case p.Year < o.Year || (p.Year == o.Year && p.Quarter < o.Quarter):
+		return -1
+	case p == o:
+		return 0
+	}
+	return 1
+}
@@ -3,15 +3,36 @@ package port
 // report domain interface
 type Report interface {
 	Validate() error
-	GetParsedFile(filename string) (map[string]Report, error)
-	GetDB(repo Repository) (map[string]Report, error)
+	GetParsedFile(filename string, opts ParseOptions) (map[string]Report, error)
+	GetDB(repo Repository, period Period) (map[string]Report, error)
 	String() string
 	Format() string
 	GetName() string
+	GetFields() []Field
+}
+
+// ParseOptions holds what a parsed report file is checked against
+// a zero Period or an empty Acquirer disables the corresponding check
+// LayoutVersion selects the file layout version; empty detects it from the period and record length
+// MaxErrors limits the line errors collected from a file; zero uses the default limit
+// Warn receives the problems that do not prevent the parsing; nil discards them
+// Charset and Unrepresentable select the file encoding and its policy; empty means ISO-8859-1 and fail
+type ParseOptions struct {
+	Period          Period
+	Acquirer        string
+	LayoutVersion   string
+	MaxErrors       int
+	Warn            func(format string, args ...interface{})
+	Charset         string
+	Unrepresentable string
 }
 
 // repository domain interface
 type Repository interface {
 	FindAll(dest interface{}, limit int, offset int, orderBy string, conditions ...interface{}) error
 	FindByPrimaryKey(dest interface{}, keyName string, keyValue interface{}) error
+	Where(query interface{}, args ...interface{}) Repository
+	Select(columns string) Repository
+	Save(value interface{}) error
+	Update(model interface{}, column string, value interface{}, conditions ...interface{}) error
 }
@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	"fmt"
+
+	"github.com/lavinas/cadoc6334/internal/port"
+	"github.com/shopspring/decimal"
+)
+
+// FieldDifference holds one field whose value differs between the database and the file
+// AbsDelta (file - db) and RelDelta (percent of the db value) are set for amounts and rates
+// WithinTolerance is set when a configured tolerance accepts the difference
+type FieldDifference struct {
+	Field           string `json:"field"`
+	DB              string `json:"db"`
+	File            string `json:"file"`
+	AbsDelta        string `json:"abs_delta,omitempty"`
+	RelDelta        string `json:"rel_delta,omitempty"`
+	WithinTolerance bool   `json:"within_tolerance"`
+}
+
+// String returns a one line representation of the field difference
+func (fd *FieldDifference) String() string {
+	ret := fmt.Sprintf("%s: DB %q File %q", fd.Field, fd.DB, fd.File)
+	if fd.AbsDelta != "" {
+		ret += fmt.Sprintf(" delta %s", fd.AbsDelta)
+	}
+	if fd.RelDelta != "" {
+		ret += fmt.Sprintf(" (%s%%)", fd.RelDelta)
+	}
+	if fd.WithinTolerance {
+		ret += " within tolerance"
+	}
+	return ret
+}
+
+// compareFields compares two records field by field and returns the fields that differ
+func compareFields(db port.Report, file port.Report) []*FieldDifference {
+	var diffs []*FieldDifference
+	fileFields := make(map[string]port.Field)
+	for _, f := range file.GetFields() {
+		fileFields[f.Name] = f
+	}
+	seen := make(map[string]bool)
+	for _, dbField := range db.GetFields() {
+		seen[dbField.Name] = true
+		fileField, exists := fileFields[dbField.Name]
+		if !exists {
+			diffs = append(diffs, &FieldDifference{Field: dbField.Name, DB: dbField.Value})
+			continue
+		}
+		if d := compareField(dbField, fileField); d != nil {
+			diffs = append(diffs, d)
+		}
+	}
+	for _, fileField := range file.GetFields() {
+		if !seen[fileField.Name] {
+			diffs = append(diffs, &FieldDifference{Field: fileField.Name, File: fileField.Value})
+		}
+	}
+	return diffs
+}
+
+// compareField compares one field of both sides; it returns nil when they match
+func compareField(db port.Field, file port.Field) *FieldDifference {
+	if db.IsDecimal() && file.IsDecimal() {
+		if db.Number.Equal(file.Number) {
+			return nil
+		}
+		delta := file.Number.Sub(db.Number)
+		diff := &FieldDifference{Field: db.Name, DB: db.Value, File: file.Value, AbsDelta: delta.String()}
+		if !db.Number.IsZero() {
+			diff.RelDelta = delta.Div(db.Number.Abs()).Mul(decimal.NewFromInt(100)).Round(4).String()
+		}
+		return diff
+	}
+	if db.Value == file.Value {
+		return nil
+	}
+	return &FieldDifference{Field: db.Name, DB: db.Value, File: file.Value}
+}
@@ -0,0 +1,74 @@
+package usecase
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+
+	"github.com/lavinas/cadoc6334/internal/domain"
+	"github.com/lavinas/cadoc6334/internal/port"
+)
+
+// DuplicatePolicy describes how duplicated PIX records are detected and handled
+// Key lists the record fields identifying a transaction within a day, e.g. DataTransacao and NSU (default)
+// or CodigoCliente, ValorBrutoOriginal and Hora; the first record of a key is kept and the others left out
+// Report receives the duplicates found as CSV; Flag sets the duplicated column of their rows
+type DuplicatePolicy struct {
+	Key    []string `yaml:"key"`
+	Report string   `yaml:"report"`
+	Flag   bool     `yaml:"flag"`
+}
+
+// Validate checks the duplicate key fields
+func (p DuplicatePolicy) Validate() error {
+	_, err := domain.NewPixDuplicates(p.Key)
+	return err
+}
+
+// detector returns a new duplicate detector keyed on the policy fields
+func (p DuplicatePolicy) detector() (*domain.PixDuplicates, error) {
+	return domain.NewPixDuplicates(p.Key)
+}
+
+// handle writes the duplicate report and flags the duplicated rows, as configured
+// the outcome is returned as a DUPLICATES result, or nil when there is nothing to tell
+func (p DuplicatePolicy) handle(repo port.Repository, found []*domain.PixDuplicate) *ReportResult {
+	if len(found) == 0 && p.Report == "" {
+		return nil
+	}
+	result := NewReportResult("DUPLICATES", p.Report)
+	result.Records = int64(len(found))
+	if p.Report != "" {
+		if err := writeDuplicates(p.Report, found); err != nil {
+			result.fail(err)
+		}
+	}
+	if !p.Flag {
+		return result
+	}
+	for _, dup := range found {
+		if err := dup.Flag(repo); err != nil {
+			result.fail(fmt.Errorf("error flagging source NSU %s: %w", dup.Source, err))
+		}
+	}
+	return result
+}
+
+// writeDuplicates writes the duplicates found as CSV, one line per duplicated record
+func writeDuplicates(path string, found []*domain.PixDuplicate) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating duplicate report: %w", err)
+	}
+	cw := csv.NewWriter(file)
+	cw.Write([]string{"day", "key", "nsu", "original_nsu", "row_id"})
+	for _, dup := range found {
+		cw.Write([]string{dup.Day.Format("2006-01-02"), dup.Key, dup.Source, dup.Original, dup.RowID})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		file.Close()
+		return fmt.Errorf("error writing duplicate report: %w", err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,148 @@
+package usecase
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Reconciliation report formats
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+	FormatHTML = "html"
+)
+
+// Export writes the reconciliation result to a file in the given format
+// an empty format is inferred from the file extension
+func (r *ReconciliationResult) Export(path string, format string) error {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+	var write func(io.Writer) error
+	switch format {
+	case FormatJSON:
+		write = r.WriteJSON
+	case FormatCSV:
+		write = r.WriteCSV
+	case FormatHTML, "htm":
+		write = r.WriteHTML
+	default:
+		return fmt.Errorf("unknown reconciliation report format %q: expected json, csv or html", format)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating reconciliation report: %w", err)
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return fmt.Errorf("error writing reconciliation report: %w", err)
+	}
+	return file.Close()
+}
+
+// WriteJSON writes the reconciliation result as indented JSON
+func (r *ReconciliationResult) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteCSV writes the reconciliation result as CSV, one line per discrepancy
+func (r *ReconciliationResult) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"report", "file", "kind", "key", "field", "db", "file_value", "abs_delta", "rel_delta"}); err != nil {
+		return err
+	}
+	for _, rep := range r.Reports {
+		for _, key := range rep.MissingInFile {
+			cw.Write([]string{rep.Name, rep.File, "missing_in_file", key, "", "", "", "", ""})
+		}
+		for _, key := range rep.MissingInDB {
+			cw.Write([]string{rep.Name, rep.File, "missing_in_db", key, "", "", "", "", ""})
+		}
+		for _, d := range rep.Differences {
+			for _, f := range d.Fields {
+				cw.Write([]string{rep.Name, rep.File, "mismatch", d.Key, f.Field, f.DB, f.File, f.AbsDelta, f.RelDelta})
+			}
+		}
+		for _, d := range rep.Tolerated {
+			for _, f := range d.Fields {
+				cw.Write([]string{rep.Name, rep.File, "within_tolerance", d.Key, f.Field, f.DB, f.File, f.AbsDelta, f.RelDelta})
+			}
+		}
+		for _, e := range rep.HeaderErrors {
+			cw.Write([]string{rep.Name, rep.File, "header_error", "", "", e, "", "", ""})
+		}
+		for _, e := range rep.Errors {
+			cw.Write([]string{rep.Name, rep.File, "error", "", "", e, "", "", ""})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteHTML writes the reconciliation result as a self-contained HTML page
+func (r *ReconciliationResult) WriteHTML(w io.Writer) error {
+	return htmlReport.Execute(w, r)
+}
+
+var htmlReport = template.Must(template.New("reconciliation").Funcs(template.FuncMap{
+	"itoa": strconv.Itoa,
+}).Parse(`<!DOCTYPE html>
+<html lang="pt-BR">
+<head>
+<meta charset="utf-8">
+<title>CADOC 6334 reconciliation {{.Period}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #bbb; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #eee; }
+td.num { text-align: right; }
+.ok { color: #1a7f37; }
+.bad { color: #b42318; font-weight: bold; }
+pre { margin: 0; white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>CADOC 6334 reconciliation</h1>
+<p>Institution: {{.Institution}}<br>Period: {{.Period}}<br>Generated at: {{.GeneratedAt.Format "2006-01-02 15:04:05"}}</p>
+<h2>Totals</h2>
+<table>
+<tr><th>Report</th><th>File</th><th>DB records</th><th>File records</th><th>Matched</th><th>Missing in file</th><th>Missing in DB</th><th>Differences</th><th>Within tolerance</th><th>Header errors</th><th>Errors</th><th>Status</th></tr>
+{{range .Reports}}<tr>
+<td>{{.Name}}</td><td>{{.File}}</td>
+<td class="num">{{.DBRecords}}</td><td class="num">{{.FileRecords}}</td><td class="num">{{.Matched}}</td>
+<td class="num">{{len .MissingInFile}}</td><td class="num">{{len .MissingInDB}}</td><td class="num">{{len .Differences}}</td><td class="num">{{len .Tolerated}}</td><td class="num">{{len .HeaderErrors}}</td><td class="num">{{len .Errors}}</td>
+<td>{{if or .HasDiscrepancies .HeaderErrors .Errors}}<span class="bad">discrepancies</span>{{else if .HasTolerated}}<span class="ok">within tolerance</span>{{else}}<span class="ok">ok</span>{{end}}</td>
+</tr>
+{{end}}</table>
+{{range .Reports}}{{if or .HasDiscrepancies .HeaderErrors .Errors .HasTolerated}}
+<h2>{{.Name}}</h2>
+{{if .HeaderErrors}}<h3>Header errors</h3>
+<table>{{range .HeaderErrors}}<tr><td><pre>{{.}}</pre></td></tr>{{end}}</table>{{end}}
+{{if .Errors}}<h3>Errors</h3>
+<table>{{range .Errors}}<tr><td><pre>{{.}}</pre></td></tr>{{end}}</table>{{end}}
+{{if .MissingInFile}}<h3>Missing in file ({{itoa (len .MissingInFile)}})</h3>
+<table><tr><th>Key</th></tr>{{range .MissingInFile}}<tr><td>{{.}}</td></tr>{{end}}</table>{{end}}
+{{if .MissingInDB}}<h3>Missing in DB ({{itoa (len .MissingInDB)}})</h3>
+<table><tr><th>Key</th></tr>{{range .MissingInDB}}<tr><td>{{.}}</td></tr>{{end}}</table>{{end}}
+{{if .Differences}}<h3>Differences ({{itoa (len .Differences)}})</h3>
+<table><tr><th>Key</th><th>Field</th><th>DB</th><th>File</th><th>Abs. delta</th><th>Rel. delta (%)</th></tr>
+{{range .Differences}}{{$key := .Key}}{{range .Fields}}<tr><td>{{$key}}</td><td>{{.Field}}</td><td><pre>{{.DB}}</pre></td><td><pre>{{.File}}</pre></td><td class="num">{{.AbsDelta}}</td><td class="num">{{.RelDelta}}</td></tr>
+{{end}}{{end}}</table>{{end}}
+{{if .Tolerated}}<h3>Within tolerance ({{itoa (len .Tolerated)}})</h3>
+<table><tr><th>Key</th><th>Field</th><th>DB</th><th>File</th><th>Abs. delta</th><th>Rel. delta (%)</th></tr>
+{{range .Tolerated}}{{$key := .Key}}{{range .Fields}}<tr><td>{{$key}}</td><td>{{.Field}}</td><td><pre>{{.DB}}</pre></td><td><pre>{{.File}}</pre></td><td class="num">{{.AbsDelta}}</td><td class="num">{{.RelDelta}}</td></tr>
+{{end}}{{end}}</table>{{end}}
+{{end}}{{end}}
+</body>
+</html>
+`))
@@ -1,224 +1,294 @@
 package usecase
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sort"
 	"time"
 
 	"github.com/lavinas/cadoc6334/internal/domain"
 	"github.com/lavinas/cadoc6334/internal/port"
-	"golang.org/x/text/encoding/charmap"
-)
-
-const (
-	outPath = "./files/out"
 )
 
 // GenerateCase represents the use case for generating data
 type GenerateCase struct {
-	repo port.Repository
+	repo        port.Repository
+	outPath     string
+	period      port.Period
+	institution *domain.Institution
+	document    string
+	reports     []string
+	profile     OutputProfile
 }
 
 // NewGenerateCase creates a new instance of GenerateCase
-func NewGenerateCase(repo port.Repository) *GenerateCase {
-	return &GenerateCase{repo: repo}
-}
-
-// Execute all tst
-func (ge *GenerateCase) ExecuteAll() {
-	files := []string{
-		"PIX.TXT",
-	}
-	for _, file := range files {
-		filename := fmt.Sprintf("%s/%s", outPath, file)
-		ge.GeneratePixReport(filename)
+// document selects the CADOC document whose reports are generated
+// reports restricts generation to the given report names; empty means all reports
+// profile sets how the files are written for the transmission target
+func NewGenerateCase(repo port.Repository, outPath string, period port.Period, institution *domain.Institution, document string, reports []string, profile OutputProfile) *GenerateCase {
+	return &GenerateCase{
+		repo:        repo,
+		outPath:     outPath,
+		period:      period,
+		institution: institution,
+		document:    document,
+		reports:     reports,
+		profile:     profile,
 	}
 }
 
-// Execute executes the generate use case
-func (ge *GenerateCase) ExecuteAll2() {
-	// Implement the logic for generating data here
+// ExecuteAll generates the PIX DIMP daily files of the transaction dates between from and to, both inclusive
+// a zero from or to leaves the range open on that side
+// incremental only rewrites the days not yet generated or whose content changed since the last run
+// duplicates sets how duplicated records are detected, reported and flagged
+func (ge *GenerateCase) ExecuteAll(from time.Time, to time.Time, incremental bool, duplicates DuplicatePolicy) *Result {
+	result := NewResult("PIX", ge.outPath)
+	result.Add(ge.GeneratePixReport(from, to, incremental, duplicates)...)
+	return result
+}
 
-	files := []string{
-		"RANKING.TXT",
-		"CONCCRED.TXT",
-		"INFRESTA.TXT",
-		"INFRTERM.TXT",
-		"DESCONTO.TXT",
-		"INTERCAM.TXT",
-		"SEGMENTO.TXT",
-		"LUCRCRED.TXT",
-		"CONTATOS.TXT",
-		"DATABASE.TXT",
-	}
-	reports := []port.Report{
-		domain.NewRanking(),
-		domain.NewConccred(),
-		domain.NewInfresta(),
-		domain.NewInfrterm(),
-		domain.NewDiscount(),
-		domain.NewIntercam(),
-		domain.NewSegment(),
-		domain.NewLucrCred(),
-		domain.NewContact(),
-		domain.NewDatabase(),
-	}
-	for i, file := range files {
-		filename := fmt.Sprintf("%s/%s", outPath, file)
-		if file == "DATABASE.TXT" {
-			ge.GenerateDatabaseReport(filename)
+// ExecuteAll2 generates the report package of the institution for the document
+// a report is not generated when a report it depends on failed
+func (ge *GenerateCase) ExecuteAll2() *Result {
+	result := NewResult(ge.institution.String(), ge.outPath)
+	failed := make(map[string]bool)
+	for _, spec := range domain.GetReports(ge.document) {
+		if !isSelected(ge.reports, spec.Name) {
 			continue
 		}
-		ge.GenerateReport(reports[i], filename)
+		filename := fmt.Sprintf("%s/%s", ge.outPath, spec.File)
+		rep := ge.generate(spec, filename, failed)
+		if !rep.Status.IsOK() {
+			failed[spec.Name] = true
+		}
+		result.Add(rep)
 	}
+	return result
 }
 
-// GeneratePixReport generates the PIX report
-func (ge *GenerateCase) GeneratePixReport1(filename string) {
-	// Implement the logic for generating data here
-	fmt.Printf("Generating data for %s\n", filename)
-	defer fmt.Println("---------------------------------------------------------------------------------------------------------")
-	// read db data
-	pix := domain.NewPix()
-	lines, err := pix.GetDB(ge.repo)
-	if err != nil {
-		fmt.Printf("Error getting data from DB: %s\n", err)
-		return
-	}
-	// sort lines
-	order := make([]string, 0, len(lines))
-	for k := range lines {
-		order = append(order, k)
-	}
-	sort.Strings(order)
-	// open file for writing
-	file, err := os.Create(filename)
-	if err != nil {
-		fmt.Printf("Error creating file: %s\n", err)
-		return
+// generate generates a report unless a report it depends on failed
+func (ge *GenerateCase) generate(spec *domain.ReportSpec, filename string, failed map[string]bool) *ReportResult {
+	for _, dep := range spec.DependsOn {
+		if failed[dep] {
+			return NewReportResult(spec.Name, filename).fail(fmt.Errorf("not generated: report %s failed", dep))
+		}
 	}
-	defer file.Close()
-	// Print header
-	header := domain.NewPixHeader(time.Now())
-	headerLine := header.Format()
-	file.Write([]byte(headerLine))
-	file.Write([]byte("\n"))
-	// print lines
-	for _, k := range order {
-		r := lines[k].Format()
-		// Convert to desired encoding
-		file.Write([]byte(r))
-		file.Write([]byte("\n"))
+	return ge.GenerateReport(spec, filename)
+}
+
+// ExecuteInstitutions generates the CADOC report package of each institution
+// every institution gets its own database filters, output subdirectory and header CNPJ
+func (ge *GenerateCase) ExecuteInstitutions(institutions []domain.Institution) []*Result {
+	results := make([]*Result, 0, len(institutions))
+	for i := range institutions {
+		inst := &institutions[i]
+		outPath := filepath.Join(ge.outPath, inst.GetOutputDir())
+		fmt.Printf("Generating reports for institution %s into %s\n", inst, outPath)
+		if err := os.MkdirAll(outPath, 0o755); err != nil {
+			result := NewResult(inst.String(), outPath)
+			result.Add(NewReportResult("*", outPath).fail(fmt.Errorf("error creating output directory: %w", err)))
+			results = append(results, result)
+			continue
+		}
+		uc := NewGenerateCase(ge.repo, outPath, ge.period, inst, ge.document, ge.reports, ge.profile)
+		results = append(results, uc.ExecuteAll2())
 	}
-	// Print trailer
-	trailer := domain.NewPixTrailer(int64(len(lines)))
-	trailerLine := trailer.Format()
-	file.Write([]byte(trailerLine))
-	file.Write([]byte("\n"))
+	return results
 }
 
-// GeneratePixReport2 generates the PIX report
-func (ge *GenerateCase) GeneratePixReport(filename string) {
-	fmt.Printf("[%s]Generating data for %s\n", time.Now().Format("2006-01-02 15:04:05"), filename)
+// GeneratePixReport generates one PIX DIMP file per transaction date between from and to
+// records are streamed from the database into the day files, so memory does not grow with the number of records
+// the NSU and authorization code are derived from the source NSU before formatting, and duplicated records
+// are left out of the files, reported and flagged by the duplicate policy
+// a record whose NSU collides with another of the day, or whose fields cannot be normalized,
+// is left out of its file and reported as rejected with the reason
+// incremental runs only regenerate the days selected by pixDays, recording each file written on the
+// generation-control table; the other days are reported unchanged
+func (ge *GenerateCase) GeneratePixReport(from time.Time, to time.Time, incremental bool, duplicates DuplicatePolicy) []*ReportResult {
+	fmt.Printf("[%s]Generating PIX data into %s\n", time.Now().Format("2006-01-02 15:04:05"), ge.outPath)
 	defer fmt.Println("---------------------------------------------------------------------------------------------------------")
-	// read db data
-	pix := domain.NewPix()
-	lines, err := pix.GetDBOrdered(ge.repo)
+	detector, err := duplicates.detector()
 	if err != nil {
-		fmt.Printf("Error getting data from DB: %s\n", err)
-		return
+		return []*ReportResult{NewReportResult("PIX", ge.outPath).fail(err)}
+	}
+	var results []*ReportResult
+	spans := [][2]time.Time{{from, to}}
+	var sources map[string]*domain.PixSource
+	if incremental {
+		var days []time.Time
+		if days, sources, results, err = ge.pixDays(from, to); err != nil {
+			return []*ReportResult{NewReportResult("PIX", ge.outPath).fail(err)}
+		}
+		spans = spans[:0]
+		for _, day := range days {
+			spans = append(spans, [2]time.Time{day, day})
+		}
 	}
-	fmt.Printf("[%s]Database got data successfully with %d lines.\n", time.Now().Format("2006-01-02 15:04:05"), len(lines))
-	// control var
-	var last_date = time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)
-	var file *os.File
-	var count int64 = 0
-	// loop
-	for _, k := range lines {
-		if k.(*domain.Pix).DataTransacao.After(last_date) {
-			if file != nil {
-				// Print trailer
-				trailer := domain.NewPixTrailer(count)
-				trailerLine := trailer.Format()
-				file.Write([]byte(trailerLine))
-				file.Write([]byte("\n"))
-				file.Close()
+	var lastDate = time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)
+	var writer *fileWriter
+	var result *ReportResult
+	var trailer *domain.PixTrailer
+	var read, dups int64
+	transformer := domain.NewPixTransformer(detector)
+	// closeDay writes the trailer of the current day file, moves it into place and writes its manifest
+	closeDay := func() {
+		if writer == nil {
+			return
+		}
+		defer func() { writer = nil }()
+		if dups > 0 {
+			result.warn("%d duplicate record(s) left out", dups)
+			dups = 0
+		}
+		if err := writer.WriteLine(trailer.Format()); err != nil {
+			writer.Abort()
+			result.fail(err)
+			return
+		}
+		checksum, err := writer.Commit()
+		if err != nil {
+			result.fail(err)
+			return
+		}
+		result.Checksum = checksum
+		if err := newPixManifest(result.Path, checksum, trailer).Write(); err != nil {
+			result.fail(err)
+			return
+		}
+		if source := sources[lastDate.Format("2006-01-02")]; source != nil {
+			generation := domain.NewPixGeneration(lastDate, filepath.Base(result.Path), result.Records, checksum, source)
+			if err := generation.Save(ge.repo); err != nil {
+				result.fail(fmt.Errorf("error saving generation control: %w", err))
 			}
-			// close previous file
-			file.Close()
-			// reset count
-			count = 0
-			// update date
-			last_date = k.(*domain.Pix).DataTransacao
-			// create new file
-			filename := fmt.Sprintf("%s/bh_transacoes_%s.txt", outPath, k.(*domain.Pix).DataTransacao.Format("2006-01-02"))
-			file, err = os.Create(filename)
+		}
+	}
+	// write errors stop the generation and are told apart from database errors
+	var writeErr error
+	write := func(record *domain.Pix) error {
+		read++
+		if record.DataTransacao.After(lastDate) {
+			closeDay()
+			lastDate = record.DataTransacao
+			filename := filepath.Join(ge.outPath, domain.PixFileName(record.DataTransacao))
 			fmt.Printf("[%s]Creating file: %s\n", time.Now().Format("2006-01-02 15:04:05"), filename)
-			if err != nil {
-				fmt.Printf("Error creating file: %s\n", err)
-				return
+			result = NewReportResult("PIX", filename)
+			results = append(results, result)
+			trailer = domain.NewPixTrailer(ge.profile.PixTrailerLayout())
+			if writer, writeErr = newFileWriter(filename, ge.profile); writeErr != nil {
+				return writeErr
+			}
+			if writeErr = writer.WriteLine(domain.NewPixHeader(record.DataTransacao).Format()); writeErr != nil {
+				return writeErr
 			}
-			// Print header
-			header := domain.NewPixHeader(k.(*domain.Pix).DataTransacao)
-			headerLine := header.Format()
-			file.Write([]byte(headerLine))
-			file.Write([]byte("\n"))
 		}
-		// print lines
-		r := k.Format()
-		// Convert to desired encoding
-		file.Write([]byte(r))
-		file.Write([]byte("\n"))
-		count += 1
+		if err := transformer.Transform(record); err != nil {
+			var dup *domain.PixDuplicate
+			if errors.As(err, &dup) {
+				dups++
+				return nil
+			}
+			result.reject(pixRecordName(record), err)
+			return nil
+		}
+		line, err := record.FormatLine()
+		if err != nil {
+			result.reject(pixRecordName(record), err)
+			return nil
+		}
+		if writeErr = writer.WriteLine(line); writeErr != nil {
+			return writeErr
+		}
+		result.Records++
+		trailer.Add(record)
+		return nil
 	}
-	if file != nil {
-		// Print trailer
-		trailer := domain.NewPixTrailer(count)
-		trailerLine := trailer.Format()
-		file.Write([]byte(trailerLine))
-		file.Write([]byte("\n"))
-		file.Close()
+	for _, span := range spans {
+		if err = domain.NewPix().EachDBOrdered(ge.repo, span[0], span[1], domain.PixBatchSize, write); err != nil {
+			break
+		}
 	}
+	if err != nil {
+		if writer != nil {
+			writer.Abort()
+		}
+		if result == nil {
+			result = NewReportResult("PIX", ge.outPath)
+			results = append(results, result)
+		}
+		if err != writeErr {
+			err = fmt.Errorf("error getting data from DB: %w", err)
+		}
+		result.fail(err)
+		return results
+	}
+	closeDay()
+	fmt.Printf("[%s]Database got data successfully with %d lines.\n", time.Now().Format("2006-01-02 15:04:05"), read)
+	if rep := duplicates.handle(ge.repo, detector.Found); rep != nil {
+		results = append(results, rep)
+	}
+	return results
 }
 
-// GenerateDatabaseReport generates the database report
-func (ge *GenerateCase) GenerateDatabaseReport(filename string) {
-	// Implement the logic for generating data here
-	fmt.Printf("Generating data for %s\n", filename)
-	defer fmt.Println("---------------------------------------------------------------------------------------------------------")
-	// read db data
-	db := domain.NewDatabase()
-	// open file for writing
-	file, err := os.Create(filename)
+// pixRecordName identifies a PIX record on the generation results by its date and source NSU
+func pixRecordName(record *domain.Pix) string {
+	return fmt.Sprintf("record %s nsu %s", record.DataTransacao.Format("2006-01-02"), record.NSUSource)
+}
+
+// pixDays selects the days an incremental run regenerates between from and to: the days never generated,
+// those whose source rows changed since, and those whose file is missing or no longer the one generated
+// the source state of the days is returned to be recorded, and the days left as they are as unchanged results
+func (ge *GenerateCase) pixDays(from time.Time, to time.Time) ([]time.Time, map[string]*domain.PixSource, []*ReportResult, error) {
+	generated, err := domain.GetPixGenerations(ge.repo, from, to)
 	if err != nil {
-		fmt.Printf("Error creating file: %s\n", err)
-		return
+		return nil, nil, nil, fmt.Errorf("error getting generation control: %w", err)
 	}
-	defer file.Close()
-	// prepare encoder
-	encoder := charmap.ISO8859_1.NewEncoder()
-	// print line
-	line := db.Format()
-	out, err := encoder.Bytes([]byte(line))
+	sources, err := domain.GetPixSources(ge.repo, from, to)
 	if err != nil {
-		fmt.Printf("Error converting line to ISO-8859-1: %s\n", err)
-		return
+		return nil, nil, nil, fmt.Errorf("error getting source state: %w", err)
 	}
-	file.Write(out)
-	file.Write([]byte("\n"))
+	var days []time.Time
+	var unchanged []*ReportResult
+	byDay := make(map[string]*domain.PixSource, len(sources))
+	for _, source := range sources {
+		byDay[source.GetKey()] = source
+		filename := filepath.Join(ge.outPath, domain.PixFileName(source.DataTransacao))
+		last := generated[source.GetKey()]
+		if last == nil || !last.Current(source) {
+			days = append(days, source.DataTransacao)
+			continue
+		}
+		if checksum, err := fileChecksum(filename); err != nil || checksum != last.Checksum {
+			days = append(days, source.DataTransacao)
+			continue
+		}
+		result := NewReportResult("PIX", filename)
+		result.Status = StatusUnchanged
+		result.Records = last.Records
+		result.Checksum = last.Checksum
+		unchanged = append(unchanged, result)
+	}
+	return days, byDay, unchanged, nil
 }
 
 // GenerateReport executes the generate use case for a specific report
-func (ge *GenerateCase) GenerateReport(report port.Report, filename string) {
-	// Implement the logic for generating data here
+func (ge *GenerateCase) GenerateReport(spec *domain.ReportSpec, filename string) *ReportResult {
 	fmt.Printf("Generating data for %s\n", filename)
 	defer fmt.Println("---------------------------------------------------------------------------------------------------------")
-	// read db data
-	lines, err := report.GetDB(ge.repo)
+	result := NewReportResult(spec.Name, filename)
+	layout, err := ge.layout(spec.Name)
+	if err != nil {
+		return result.fail(err)
+	}
+	enc, err := ge.profile.GetEncoding()
+	if err != nil {
+		return result.fail(err)
+	}
+	// read data from the report source
+	lines, err := spec.GetData(ge.repo, ge.institution, ge.period)
 	if err != nil {
-		fmt.Printf("Error getting data from DB: %s\n", err)
-		return
+		return result.fail(fmt.Errorf("error getting data from DB: %w", err))
 	}
 	// sort lines
 	order := make([]string, 0, len(lines))
@@ -227,34 +297,49 @@ func (ge *GenerateCase) GenerateReport(report port.Report, filename string) {
 	}
 	sort.Strings(order)
 	// open file for writing
-	file, err := os.Create(filename)
+	writer, err := newFileWriter(filename, ge.profile)
 	if err != nil {
-		fmt.Printf("Error creating file: %s\n", err)
-		return
+		return result.fail(err)
 	}
-	defer file.Close()
-	// prepare encoder
-	encoder := charmap.ISO8859_1.NewEncoder()
 	// print header
-	header := domain.NewHeader(report.GetName(), int64(len(lines)))
-	headerLine := header.Format()
-	out, err := encoder.Bytes([]byte(headerLine))
-	if err != nil {
-		fmt.Printf("Error converting header to ISO-8859-1: %s\n", err)
-		return
+	if spec.Header {
+		header, err := domain.NewHeader(spec.Name, ge.institution.CNPJBase, int64(len(lines))).FormatLine(enc)
+		if err != nil {
+			writer.Abort()
+			return result.fail(fmt.Errorf("header: %w", err))
+		}
+		if err := writer.WriteLine(header); err != nil {
+			writer.Abort()
+			return result.fail(fmt.Errorf("header: %w", err))
+		}
 	}
-	file.Write(out)
-	file.Write([]byte("\n"))
 	// print lines
 	for _, k := range order {
-		r := lines[k].Format()
-		// Convert to desired encoding
-		out, err := encoder.Bytes([]byte(r))
+		line, err := layout.FormatLine(lines[k], enc)
 		if err != nil {
-			fmt.Printf("Error converting line to ISO-8859-1: %s\n", err)
-			return
+			writer.Abort()
+			return result.fail(fmt.Errorf("record %s: %w", k, err))
 		}
-		file.Write(out)
-		file.Write([]byte("\n"))
+		if err := writer.WriteLine(line); err != nil {
+			writer.Abort()
+			return result.fail(fmt.Errorf("record %s: %w", k, err))
+		}
+	}
+	checksum, err := writer.Commit()
+	if err != nil {
+		return result.fail(err)
+	}
+	result.Records = int64(len(order))
+	result.Checksum = checksum
+	return result
+}
+
+// layout returns the layout version of a file in force for the reference period
+func (ge *GenerateCase) layout(name string) (*domain.Layout, error) {
+	layout, err := domain.LayoutFor(name, ge.period)
+	if err != nil {
+		return nil, err
 	}
+	fmt.Printf("Using layout %s version %s\n", name, layout.Version)
+	return layout, nil
 }
@@ -0,0 +1,22 @@
+package usecase
+
+import (
+	"fmt"
+
+	"github.com/lavinas/cadoc6334/internal/domain"
+)
+
+// CheckLayouts prints the registered file layouts and proves that their Format and Parse are inverses
+func CheckLayouts() *Result {
+	result := NewResult("", "layouts")
+	for _, l := range domain.GetLayouts() {
+		fmt.Println(l)
+		rep := NewReportResult(l.Name, "")
+		if err := l.SelfCheck(); err != nil {
+			rep.fail(fmt.Errorf("self-check: %w", err))
+		}
+		result.Add(rep)
+	}
+	fmt.Println("---------------------------------------------------------------------------------------------------------")
+	return result
+}
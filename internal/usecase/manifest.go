@@ -0,0 +1,53 @@
+package usecase
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lavinas/cadoc6334/internal/domain"
+)
+
+// manifestSuffix is appended to the name of a PIX DIMP file to name its manifest
+const manifestSuffix = ".manifest.json"
+
+// pixManifest is the sidecar control file of a PIX DIMP daily file
+// it lets the receiver check the file without parsing it
+type pixManifest struct {
+	path          string
+	File          string    `json:"file"`
+	SHA256        string    `json:"sha256"`
+	Records       int64     `json:"records"`
+	ValorBrutoSum string    `json:"valor_bruto_original_sum"`
+	ValorMDRSum   string    `json:"valor_mdr_original_sum"`
+	TrailerLayout string    `json:"trailer_layout"`
+	GeneratedAt   time.Time `json:"generated_at"`
+}
+
+// newPixManifest creates the manifest of a committed PIX DIMP file from its checksum and trailer
+func newPixManifest(path string, checksum string, trailer *domain.PixTrailer) *pixManifest {
+	return &pixManifest{
+		path:          path + manifestSuffix,
+		File:          filepath.Base(path),
+		SHA256:        checksum,
+		Records:       trailer.TotalRecords,
+		ValorBrutoSum: trailer.ValorBrutoTotal.StringFixed(2),
+		ValorMDRSum:   trailer.ValorMDRTotal.StringFixed(2),
+		TrailerLayout: string(trailer.Layout),
+		GeneratedAt:   time.Now(),
+	}
+}
+
+// Write writes the manifest next to its file as indented JSON
+func (m *pixManifest) Write() error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding manifest: %w", err)
+	}
+	if err := os.WriteFile(m.path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("error writing manifest: %w", err)
+	}
+	return nil
+}
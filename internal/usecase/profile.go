@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	"fmt"
+
+	"github.com/lavinas/cadoc6334/internal/domain"
+)
+
+// Line endings of the generated files
+const (
+	LineEndingLF   = "lf"
+	LineEndingCRLF = "crlf"
+)
+
+// DefaultProfile is the output profile used when none is configured
+const DefaultProfile = "default"
+
+// OutputProfile describes how the files are written for a transmission target, and read back
+// LineEnding is lf (default) or crlf; FinalNewline tells whether the last record is
+// followed by the line terminator, and defaults to true
+// Encoding is iso-8859-1 (default), windows-1252, ascii or utf-8; Unrepresentable is the policy for
+// characters the encoding cannot represent: fail (default), transliterate, replace or strip_accents
+// PixTrailer is the layout of the PIX DIMP trailer: count (default) or totals
+// PixRate is the form of the PIX DIMP MDR rate: text (default, the database value zero filled) or implied
+// (2 implied decimals); PixRateScale is the implied decimals of digits-only database rates, 2 by default
+type OutputProfile struct {
+	LineEnding      string `yaml:"line_ending"`
+	FinalNewline    *bool  `yaml:"final_newline"`
+	Encoding        string `yaml:"encoding"`
+	Unrepresentable string `yaml:"unrepresentable"`
+	PixTrailer      string `yaml:"pix_trailer"`
+	PixRate         string `yaml:"pix_rate"`
+	PixRateScale    *int32 `yaml:"pix_rate_scale"`
+}
+
+// Validate checks the output profile settings
+func (p OutputProfile) Validate() error {
+	switch p.LineEnding {
+	case "", LineEndingLF, LineEndingCRLF:
+	default:
+		return fmt.Errorf("invalid line ending %q: expected lf or crlf", p.LineEnding)
+	}
+	if _, err := domain.ParsePixTrailerLayout(p.PixTrailer); err != nil {
+		return err
+	}
+	if _, err := domain.NewPixRate(p.PixRate, p.PixRateScale); err != nil {
+		return err
+	}
+	_, err := p.GetEncoding()
+	return err
+}
+
+// GetEncoding returns the charset of the files and its policy for unrepresentable characters
+func (p OutputProfile) GetEncoding() (domain.Encoding, error) {
+	return domain.NewEncoding(p.Encoding, p.Unrepresentable)
+}
+
+// PixTrailerLayout returns the layout of the PIX DIMP trailer
+func (p OutputProfile) PixTrailerLayout() domain.PixTrailerLayout {
+	layout, _ := domain.ParsePixTrailerLayout(p.PixTrailer)
+	return layout
+}
+
+// GetPixRate returns the form and scale of the PIX DIMP MDR rate
+func (p OutputProfile) GetPixRate() domain.PixRate {
+	rate, _ := domain.NewPixRate(p.PixRate, p.PixRateScale)
+	return rate
+}
+
+// Terminator returns the line terminator written after each record
+func (p OutputProfile) Terminator() string {
+	if p.LineEnding == LineEndingCRLF {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// HasFinalNewline reports whether the last record is followed by the line terminator
+func (p OutputProfile) HasFinalNewline() bool {
+	return p.FinalNewline == nil || *p.FinalNewline
+}
@@ -1,169 +1,295 @@
 package usecase
 
 import (
+	"errors"
 	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
 
 	"github.com/lavinas/cadoc6334/internal/domain"
 	"github.com/lavinas/cadoc6334/internal/port"
-	"golang.org/x/text/encoding/charmap"
-)
-
-const (
-	inPath = "./files/in"
 )
 
 // ReconciliateCase represents the use case for checking or validating data
 type ReconciliateCase struct {
-	repo port.Repository
-	// Add any dependencies or configurations needed for the use case
+	repo          port.Repository
+	inPath        string
+	period        port.Period
+	institution   *domain.Institution
+	document      string
+	reports       []string
+	tolerances    []Tolerance
+	layoutVersion string
+	maxErrors     int
+	profile       OutputProfile
 }
 
 // NewReconciliateCase creates a new instance of ReconciliateCase
-func NewReconciliateCase(repo port.Repository) *ReconciliateCase {
+// repo may be nil when only file validation or inspection is needed
+// records outside period are rejected; a zero period accepts any period on file validation
+// institution sets the CNPJ base expected on file headers (empty skips the check) and the database filters
+// document selects the CADOC document whose files are read
+// reports restricts the run to the given report names; empty means all reports
+// tolerances set the accepted differences of amount and rate fields on reconciliation
+// layoutVersion forces the layout version of the input files; empty detects it
+// maxErrors limits the line errors reported per input file; zero uses the default limit
+// profile sets the encoding the input files were written with
+func NewReconciliateCase(repo port.Repository, inPath string, period port.Period, institution *domain.Institution, document string, reports []string, tolerances []Tolerance, layoutVersion string, maxErrors int, profile OutputProfile) *ReconciliateCase {
 	return &ReconciliateCase{
-		repo: repo,
+		repo:          repo,
+		inPath:        inPath,
+		period:        period,
+		institution:   institution,
+		document:      document,
+		reports:       reports,
+		tolerances:    tolerances,
+		layoutVersion: layoutVersion,
+		maxErrors:     maxErrors,
+		profile:       profile,
+	}
+}
+
+// ExecuteAll executes the check use case
+// it returns the per-file status and the structured reconciliation of every file
+func (uc *ReconciliateCase) ExecuteAll() (*Result, *ReconciliationResult) {
+	result := NewResult(uc.institution.String(), uc.inPath)
+	reconciliation := NewReconciliationResult(uc.institution.String(), uc.period)
+	for _, spec := range uc.specs() {
+		filename := fmt.Sprintf("%s/%s", uc.inPath, spec.File)
+		rep, rec := uc.ExecuteReport(spec, filename)
+		result.Add(rep)
+		reconciliation.Add(rec)
 	}
+	return result, reconciliation
 }
 
-// Execute2 executes the check use case
-func (uc *ReconciliateCase) ExecuteAll() {
-	files := []string{
-		"RANKING.TXT",
-		"CONCCRED.TXT",
-		"INFRESTA.TXT",
-		"INFRTERM.TXT",
-		"DESCONTO.TXT",
-		"INTERCAM.TXT",
-		"SEGMENTO.TXT",
-		"LUCRCRED.TXT",
-		"CONTATOS.TXT",
-	}
-	reports := []port.Report{
-		domain.NewRanking(),
-		domain.NewConccred(),
-		domain.NewInfresta(),
-		domain.NewInfrterm(),
-		domain.NewDiscount(),
-		domain.NewIntercam(),
-		domain.NewSegment(),
-		domain.NewLucrCred(),
-		domain.NewContact(),
-	}
-	for i, file := range files {
-		filename := fmt.Sprintf("%s/%s", inPath, file)
-		uc.ExecuteReport(reports[i], filename)
+// ValidateAll parses and validates the input files without touching the database
+func (uc *ReconciliateCase) ValidateAll() *Result {
+	result := NewResult(uc.institution.String(), uc.inPath)
+	for _, spec := range uc.specs() {
+		filename := fmt.Sprintf("%s/%s", uc.inPath, spec.File)
+		result.Add(uc.ValidateReport(spec.New(), filename))
 	}
+	return result
+}
+
+// InspectAll prints the parsed records of the input files
+func (uc *ReconciliateCase) InspectAll() *Result {
+	result := NewResult(uc.institution.String(), uc.inPath)
+	for _, spec := range uc.specs() {
+		filename := fmt.Sprintf("%s/%s", uc.inPath, spec.File)
+		result.Add(uc.InspectReport(spec.New(), filename))
+	}
+	return result
+}
+
+// specs returns the selected reports of the document whose files can be reconciled
+func (uc *ReconciliateCase) specs() []*domain.ReportSpec {
+	var ret []*domain.ReportSpec
+	for _, spec := range domain.GetReports(uc.document) {
+		if spec.Reconcilable() && isSelected(uc.reports, spec.Name) {
+			ret = append(ret, spec)
+		}
+	}
+	return ret
+}
+
+// parseOptions returns what the parsed files are checked against
+func (uc *ReconciliateCase) parseOptions() port.ParseOptions {
+	return port.ParseOptions{
+		Period:          uc.period,
+		Acquirer:        uc.institution.CNPJBase,
+		LayoutVersion:   uc.layoutVersion,
+		MaxErrors:       uc.maxErrors,
+		Charset:         uc.profile.Encoding,
+		Unrepresentable: uc.profile.Unrepresentable,
+	}
+}
+
+// ValidateReport parses a report file and validates its records
+func (uc *ReconciliateCase) ValidateReport(report port.Report, filename string) *ReportResult {
+	fmt.Printf("Validating %s\n", filename)
+	defer fmt.Println("---------------------------------------------------------------------------------------------------------")
+	result, filed := uc.parseFile(report, filename)
+	if result.Status != StatusOK {
+		return result
+	}
+	for _, e := range uc.validateReport(filed) {
+		result.fail(fmt.Errorf("file: %w", e))
+	}
+	return result
+}
+
+// InspectReport parses a report file and prints its records ordered by key
+func (uc *ReconciliateCase) InspectReport(report port.Report, filename string) *ReportResult {
+	fmt.Printf("Inspecting %s\n", filename)
+	defer fmt.Println("---------------------------------------------------------------------------------------------------------")
+	result, filed := uc.parseFile(report, filename)
+	if result.Status != StatusOK {
+		return result
+	}
+	keys := make([]string, 0, len(filed))
+	for k := range filed {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Println(filed[k].String())
+	}
+	return result
 }
 
 // ExecuteReport executes the check use case for a specific report
-func (uc *ReconciliateCase) ExecuteReport(report port.Report, filename string) {
+func (uc *ReconciliateCase) ExecuteReport(spec *domain.ReportSpec, filename string) (*ReportResult, *ReportReconciliation) {
 	fmt.Printf("Reconciliating %s\n", filename)
 	defer fmt.Println("---------------------------------------------------------------------------------------------------------")
+	rec := NewReportReconciliation(spec.Name, filename)
+	// Get file data
+	result, filed := uc.parseFile(spec.New(), filename)
+	if result.Status != StatusOK {
+		return result, rec.addErrors(result.Errors)
+	}
 	// Get db data
-	loaded, err := report.GetDB(uc.repo)
+	loaded, err := spec.GetData(uc.repo, uc.institution, uc.period)
 	if err != nil {
-		fmt.Printf("Error loading report data: %v\n", err)
-		return
+		result.fail(fmt.Errorf("error loading report data: %w", err))
+		return result, rec.addErrors(result.Errors)
 	}
-	// Get file data
-	filed, err := report.GetParsedFile(filename)
+	return uc.reconcile(result, rec, loaded, filed)
+}
+
+// ExecutePix reconciles the PIX DIMP daily files of the input directory against the database
+// with a reference period, only the files of days inside the period are reconciled; no file reconciled fails
+// duplicates sets the key of the duplicated database records, which are left out of the reconciliation
+func (uc *ReconciliateCase) ExecutePix(duplicates DuplicatePolicy) (*Result, *ReconciliationResult) {
+	result := NewResult("PIX", uc.inPath)
+	reconciliation := NewReconciliationResult("PIX", uc.period)
+	files, err := filepath.Glob(filepath.Join(uc.inPath, domain.PixFilePattern))
 	if err != nil {
-		fmt.Printf("Error parsing report file: %v\n", err)
-		return
-	}
-	// validate DB
-	if err := uc.validateReport(loaded); err != nil {
-		fmt.Println("DB validation errors found:")
-		for _, e := range err {
-			fmt.Println(e)
-		}
-		return
+		result.Add(NewReportResult("PIX", uc.inPath).fail(err))
+		return result, reconciliation
 	}
-	// validate File
-	if err := uc.validateReport(filed); err != nil {
-		fmt.Println("File validation errors found:")
-		for _, e := range err {
-			fmt.Println(e)
+	sort.Strings(files)
+	for _, filename := range files {
+		day, ok := domain.PixFileDate(filename)
+		if !ok || (!uc.period.IsZero() && (day.Before(uc.period.Start()) || !day.Before(uc.period.End()))) {
+			continue
 		}
-		return
+		rep, rec := uc.ExecutePixDay(day, filename, duplicates)
+		result.Add(rep)
+		reconciliation.Add(rec)
 	}
-	// Match and report discrepancies
-	errs := uc.match(loaded, filed)
-	if len(errs) > 0 {
-		fmt.Printf("Discrepancies found in %s:\n", filename)
-		for _, e := range errs {
-			fmt.Println(e)
+	if len(result.Reports) == 0 {
+		err := fmt.Errorf("no PIX DIMP file matching %s found", domain.PixFilePattern)
+		if !uc.period.IsZero() {
+			err = fmt.Errorf("no PIX DIMP file matching %s found for period %s", domain.PixFilePattern, uc.period)
 		}
-	} else {
-		fmt.Printf("No discrepancies found in %s\n", filename)
+		result.Add(NewReportResult("PIX", uc.inPath).fail(err))
 	}
+	return result, reconciliation
 }
 
-// validate validates records from both sources.
-func (uc *ReconciliateCase) validateReport(report map[string]port.Report) []error {
-	var errs []error
-	for key, dbRecord := range report {
-		if err := dbRecord.Validate(); err != nil {
-			errs = append(errs, fmt.Errorf("validation error for DB record with key %s: %v", key, err))
-		}
+// ExecutePixDay reconciles the PIX DIMP file of a transaction date against the database by date and NSU
+// the duplicated database records are counted on a warning
+func (uc *ReconciliateCase) ExecutePixDay(day time.Time, filename string, duplicates DuplicatePolicy) (*ReportResult, *ReportReconciliation) {
+	fmt.Printf("Reconciliating %s\n", filename)
+	defer fmt.Println("---------------------------------------------------------------------------------------------------------")
+	pix := domain.NewPix()
+	rec := NewReportReconciliation(pix.GetName(), filename)
+	// Get file data
+	result, filed := uc.parseFile(pix, filename)
+	if result.Status != StatusOK {
+		return result, rec.addErrors(result.Errors)
 	}
-	return errs
+	// Get db data
+	detector, err := duplicates.detector()
+	if err != nil {
+		result.fail(err)
+		return result, rec.addErrors(result.Errors)
+	}
+	loaded, rejected, err := pix.GetDBDay(uc.repo, day, detector)
+	if err != nil {
+		result.fail(fmt.Errorf("error loading PIX data: %w", err))
+		return result, rec.addErrors(result.Errors)
+	}
+	if len(detector.Found) > 0 {
+		result.warn("%d duplicate database record(s) left out", len(detector.Found))
+	}
+	for _, r := range rejected {
+		result.warn("db: %s left out: %v", pixRecordName(r.Record), r.Err)
+	}
+	return uc.reconcile(result, rec, loaded, filed)
 }
 
-// match compares two maps of records and returns a slice of errors for any discrepancies found.
-func (uc *ReconciliateCase) match(db map[string]port.Report, file map[string]port.Report) []error {
-	var errs []error
-	// compare lengths
-	if len(db) != len(file) {
-		return []error{fmt.Errorf("length mismatch: DB has %d records, File has %d records", len(db), len(file))}
+// reconcile validates the records of both sides and matches them
+func (uc *ReconciliateCase) reconcile(result *ReportResult, rec *ReportReconciliation, loaded map[string]port.Report, filed map[string]port.Report) (*ReportResult, *ReportReconciliation) {
+	// validate DB and File
+	for _, e := range uc.validateReport(loaded) {
+		result.fail(fmt.Errorf("db: %w", e))
+	}
+	for _, e := range uc.validateReport(filed) {
+		result.fail(fmt.Errorf("file: %w", e))
+	}
+	if result.Status != StatusOK {
+		return result, rec.addErrors(result.Errors)
+	}
+	// Match and report discrepancies
+	enc, err := uc.profile.GetEncoding()
+	if err != nil {
+		result.fail(err)
+		return result, rec.addErrors(result.Errors)
+	}
+	rec.match(loaded, filed, uc.tolerances, enc)
+	if errs := rec.Errs(); len(errs) > 0 {
+		result.Status = StatusMismatch
+		result.Errors = append(result.Errors, errs...)
+	} else if rec.HasTolerated() {
+		result.Status = StatusWithinTolerance
 	}
-	encoder := charmap.ISO8859_1.NewEncoder()
+	return result, rec
+}
 
-	for key, dbRecord := range db {
-		fileRecord, exists := file[key]
-		if !exists {
-			errs = append(errs, fmt.Errorf("db record with key %s exists in DB but not in file", key))
-			continue
-		}
-		encodedDBBytes, err := encoder.Bytes([]byte(dbRecord.String()))
-		if err != nil {
-			errs = append(errs, fmt.Errorf("error encoding DB record with key %s: %v", key, err))
-			continue
-		}
-		encoderFileBytes, err := encoder.Bytes([]byte(fileRecord.String()))
-		if err != nil {
-			errs = append(errs, fmt.Errorf("error encoding File record with key %s: %v", key, err))
-			continue
-		}
-		encodedDBString := string(encodedDBBytes)
-		encodedFileString := string(encoderFileBytes)
-		if encodedDBString != encodedFileString {
-			errs = append(errs, fmt.Errorf("mismatch for key %s:\nDB: %s\nFile: %s", key, encodedDBString, encodedFileString))
+// parseFile parses a report file into a result holding its record count and checksum
+// every line error of the file is reported on its own
+func (uc *ReconciliateCase) parseFile(report port.Report, filename string) (*ReportResult, map[string]port.Report) {
+	result := NewReportResult(report.GetName(), filename)
+	opts := uc.parseOptions()
+	opts.Warn = result.warn
+	filed, err := report.GetParsedFile(filename, opts)
+	if err != nil {
+		var headerErr *domain.HeaderError
+		var parseErrs *domain.ParseErrors
+		switch {
+		case errors.As(err, &parseErrs):
+			for _, e := range parseErrs.Errors {
+				result.fail(e)
+			}
+			if parseErrs.Truncated {
+				result.fail(fmt.Errorf("stopped after %d errors", len(parseErrs.Errors)))
+			}
+			return result, nil
+		case errors.As(err, &headerErr):
+			return result.fail(err), nil
 		}
+		return result.fail(fmt.Errorf("error parsing report file: %w", err)), nil
 	}
+	checksum, err := fileChecksum(filename)
+	if err != nil {
+		return result.fail(fmt.Errorf("error reading report file: %w", err)), nil
+	}
+	result.Records = int64(len(filed))
+	result.Checksum = checksum
+	return result, filed
+}
 
-	for key, fileRecord := range file {
-		dbRecord, exists := db[key]
-		if !exists {
-			errs = append(errs, fmt.Errorf("filerecord with key %s exists in File but not in DB", key))
-			continue
-		}
-		encodedDBBytes, err := encoder.Bytes([]byte(dbRecord.String()))
-		if err != nil {
-			errs = append(errs, fmt.Errorf("error encoding DB record with key %s: %v", key, err))
-			continue
-		}
-		encoderFileBytes, err := encoder.Bytes([]byte(fileRecord.String()))
-		if err != nil {
-			errs = append(errs, fmt.Errorf("error encoding File record with key %s: %v", key, err))
-			continue
-		}
-		encodedDBString := string(encodedDBBytes)
-		encodedFileString := string(encoderFileBytes)
-		if encodedDBString != encodedFileString {
-			errs = append(errs, fmt.Errorf("mismatch for key %s:\nDB: %s\nFile: %s", key, encodedDBString, encodedFileString))
+// validate validates records from both sources.
+func (uc *ReconciliateCase) validateReport(report map[string]port.Report) []error {
+	var errs []error
+	for key, dbRecord := range report {
+		if err := dbRecord.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("validation error for record with key %s: %v", key, err))
 		}
 	}
-
 	return errs
 }
@@ -0,0 +1,171 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lavinas/cadoc6334/internal/domain"
+	"github.com/lavinas/cadoc6334/internal/port"
+)
+
+// ReconciliationResult holds the structured outcome of reconciling report files against the database
+type ReconciliationResult struct {
+	Institution string                  `json:"institution"`
+	Period      string                  `json:"period"`
+	GeneratedAt time.Time               `json:"generated_at"`
+	Reports     []*ReportReconciliation `json:"reports"`
+}
+
+// ReportReconciliation holds the reconciliation outcome of one report file
+type ReportReconciliation struct {
+	Name          string              `json:"name"`
+	File          string              `json:"file"`
+	DBRecords     int                 `json:"db_records"`
+	FileRecords   int                 `json:"file_records"`
+	Matched       int                 `json:"matched"`
+	MissingInFile []string            `json:"missing_in_file"`
+	MissingInDB   []string            `json:"missing_in_db"`
+	Differences   []*RecordDifference `json:"differences"`
+	Tolerated     []*RecordDifference `json:"within_tolerance"`
+	HeaderErrors  []string            `json:"header_errors"`
+	Errors        []string            `json:"errors"`
+}
+
+// RecordDifference holds a record present on both sides with different field values
+// records whose differences are all within tolerance are listed apart from the mismatches
+type RecordDifference struct {
+	Key    string             `json:"key"`
+	Fields []*FieldDifference `json:"fields"`
+}
+
+// NewReconciliationResult creates a new ReconciliationResult instance
+func NewReconciliationResult(institution string, period port.Period) *ReconciliationResult {
+	return &ReconciliationResult{
+		Institution: institution,
+		Period:      period.String(),
+		GeneratedAt: time.Now(),
+	}
+}
+
+// NewReportReconciliation creates a new ReportReconciliation instance
+func NewReportReconciliation(name string, file string) *ReportReconciliation {
+	return &ReportReconciliation{
+		Name: name,
+		File: file,
+	}
+}
+
+// Add appends report reconciliations to the result
+func (r *ReconciliationResult) Add(reports ...*ReportReconciliation) {
+	r.Reports = append(r.Reports, reports...)
+}
+
+// HasDiscrepancies reports whether the report has missing or different records
+func (rr *ReportReconciliation) HasDiscrepancies() bool {
+	return len(rr.MissingInFile) > 0 || len(rr.MissingInDB) > 0 || len(rr.Differences) > 0
+}
+
+// HasTolerated reports whether the report has differences accepted by the tolerances
+func (rr *ReportReconciliation) HasTolerated() bool {
+	return len(rr.Tolerated) > 0
+}
+
+// Errs returns the discrepancies as errors, one per key
+func (rr *ReportReconciliation) Errs() []error {
+	var errs []error
+	for _, key := range rr.MissingInFile {
+		errs = append(errs, fmt.Errorf("record with key %s exists in DB but not in file", key))
+	}
+	for _, key := range rr.MissingInDB {
+		errs = append(errs, fmt.Errorf("record with key %s exists in file but not in DB", key))
+	}
+	for _, d := range rr.Differences {
+		msg := fmt.Sprintf("mismatch for key %s:", d.Key)
+		for _, f := range d.Fields {
+			msg += "\n" + f.String()
+		}
+		errs = append(errs, fmt.Errorf("%s", msg))
+	}
+	for _, e := range rr.HeaderErrors {
+		errs = append(errs, fmt.Errorf("%s", e))
+	}
+	for _, e := range rr.Errors {
+		errs = append(errs, fmt.Errorf("%s", e))
+	}
+	return errs
+}
+
+// addErrors records errors that prevented the reconciliation and returns the report
+// header errors are kept apart from errors on the data records
+func (rr *ReportReconciliation) addErrors(errs []error) *ReportReconciliation {
+	for _, e := range errs {
+		var headerErr *domain.HeaderError
+		if errors.As(e, &headerErr) {
+			rr.HeaderErrors = append(rr.HeaderErrors, e.Error())
+			continue
+		}
+		rr.Errors = append(rr.Errors, e.Error())
+	}
+	return rr
+}
+
+// match compares the records of both sides by key and field and records the discrepancies found
+// records must be representable in the encoding of the files
+// differences accepted by the tolerances are classified as within tolerance, not as mismatches
+func (rr *ReportReconciliation) match(db map[string]port.Report, file map[string]port.Report, tolerances []Tolerance, enc domain.Encoding) {
+	rr.DBRecords = len(db)
+	rr.FileRecords = len(file)
+	for _, key := range sortedKeys(db) {
+		fileRecord, exists := file[key]
+		if !exists {
+			rr.MissingInFile = append(rr.MissingInFile, key)
+			continue
+		}
+		if _, err := enc.Encode(db[key].String()); err != nil {
+			rr.Errors = append(rr.Errors, fmt.Sprintf("error encoding DB record with key %s: %v", key, err))
+			continue
+		}
+		if _, err := enc.Encode(fileRecord.String()); err != nil {
+			rr.Errors = append(rr.Errors, fmt.Sprintf("error encoding File record with key %s: %v", key, err))
+			continue
+		}
+		if fields := compareFields(db[key], fileRecord); len(fields) > 0 {
+			diff := &RecordDifference{Key: key, Fields: fields}
+			if diff.tolerate(tolerances, rr.Name) {
+				rr.Tolerated = append(rr.Tolerated, diff)
+				continue
+			}
+			rr.Differences = append(rr.Differences, diff)
+			continue
+		}
+		rr.Matched++
+	}
+	for _, key := range sortedKeys(file) {
+		if _, exists := db[key]; !exists {
+			rr.MissingInDB = append(rr.MissingInDB, key)
+		}
+	}
+}
+
+// tolerate flags the fields whose difference is within tolerance
+// it reports whether every field difference of the record is within tolerance
+func (d *RecordDifference) tolerate(tolerances []Tolerance, report string) bool {
+	all := true
+	for _, f := range d.Fields {
+		f.WithinTolerance = withinTolerance(tolerances, report, f)
+		all = all && f.WithinTolerance
+	}
+	return all
+}
+
+// sortedKeys returns the keys of a record map in ascending order
+func sortedKeys(records map[string]port.Report) []string {
+	keys := make([]string, 0, len(records))
+	for k := range records {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
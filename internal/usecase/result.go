@@ -0,0 +1,145 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ReportStatus represents the outcome of processing one report file
+type ReportStatus string
+
+const (
+	StatusOK              ReportStatus = "ok"
+	StatusFailed          ReportStatus = "failed"
+	StatusMismatch        ReportStatus = "mismatch"
+	StatusWithinTolerance ReportStatus = "within_tolerance"
+	StatusUnchanged       ReportStatus = "unchanged"
+	StatusRejected        ReportStatus = "rejected"
+)
+
+// IsOK reports whether the status is a successful outcome
+// differences within tolerance and files left unchanged are accepted
+func (s ReportStatus) IsOK() bool {
+	return s == StatusOK || s == StatusWithinTolerance || s == StatusUnchanged
+}
+
+// ReportResult holds the outcome of generating, validating or reconciling one report file
+type ReportResult struct {
+	Name     string
+	Path     string
+	Status   ReportStatus
+	Records  int64
+	Checksum string
+	Errors   []error
+	Warnings []string
+	Rejected []string
+}
+
+// NewReportResult creates a new ReportResult instance with ok status
+func NewReportResult(name string, path string) *ReportResult {
+	return &ReportResult{
+		Name:   name,
+		Path:   path,
+		Status: StatusOK,
+	}
+}
+
+// fail marks the result as failed with the given error and returns the result
+func (r *ReportResult) fail(err error) *ReportResult {
+	r.Status = StatusFailed
+	r.Errors = append(r.Errors, err)
+	return r
+}
+
+// warn records a warning that does not change the status of the result
+func (r *ReportResult) warn(format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// reject records a record left out of the file with the reason; the file is still written
+func (r *ReportResult) reject(record string, err error) {
+	if r.Status == StatusOK {
+		r.Status = StatusRejected
+	}
+	r.Rejected = append(r.Rejected, fmt.Sprintf("%s: %v", record, err))
+}
+
+// String returns a one line representation of the result
+func (r *ReportResult) String() string {
+	ret := fmt.Sprintf("%-16s %-10s records: %10d sha256: %-64s %s", r.Status, r.Name, r.Records, r.Checksum, r.Path)
+	for _, e := range r.Errors {
+		ret += "\n    " + strings.ReplaceAll(e.Error(), "\n", "\n    ")
+	}
+	for _, w := range r.Warnings {
+		ret += "\n    warning: " + w
+	}
+	for _, rej := range r.Rejected {
+		ret += "\n    rejected: " + rej
+	}
+	return ret
+}
+
+// Result holds the outcome of a use case run over several report files
+type Result struct {
+	Institution string
+	OutPath     string
+	Reports     []*ReportResult
+}
+
+// NewResult creates a new Result instance
+func NewResult(institution string, outPath string) *Result {
+	return &Result{
+		Institution: institution,
+		OutPath:     outPath,
+	}
+}
+
+// Add appends report results to the run
+func (r *Result) Add(reports ...*ReportResult) {
+	r.Reports = append(r.Reports, reports...)
+}
+
+// Failed reports whether any report of the run did not finish with ok status
+func (r *Result) Failed() bool {
+	for _, rep := range r.Reports {
+		if !rep.Status.IsOK() {
+			return true
+		}
+	}
+	return false
+}
+
+// Failures returns the reports that did not finish with ok status
+func (r *Result) Failures() []*ReportResult {
+	var ret []*ReportResult
+	for _, rep := range r.Reports {
+		if !rep.Status.IsOK() {
+			ret = append(ret, rep)
+		}
+	}
+	return ret
+}
+
+// Records returns the number of records of all ok reports
+func (r *Result) Records() int64 {
+	var total int64
+	for _, rep := range r.Reports {
+		if rep.Status.IsOK() {
+			total += rep.Records
+		}
+	}
+	return total
+}
+
+// String returns a multi line representation of the run
+func (r *Result) String() string {
+	name := r.Institution
+	if name == "" {
+		name = "-"
+	}
+	ret := fmt.Sprintf("%s: files: %d records: %d failures: %d output: %s", name, len(r.Reports), r.Records(), len(r.Failures()), r.OutPath)
+	for _, rep := range r.Reports {
+		ret += "\n  " + rep.String()
+	}
+	return ret
+}
@@ -0,0 +1,19 @@
+package usecase
+
+import "strings"
+
+// isSelected reports whether a report name is part of the selection
+// an empty selection means every report is selected
+// names are matched case-insensitively, with or without the .TXT extension
+func isSelected(selection []string, name string) bool {
+	if len(selection) == 0 {
+		return true
+	}
+	for _, s := range selection {
+		s = strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(s)), ".TXT")
+		if s == strings.ToUpper(name) {
+			return true
+		}
+	}
+	return false
+}
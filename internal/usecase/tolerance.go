@@ -0,0 +1,71 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Tolerance represents an accepted difference between database and file values of amount and rate fields
+// Report and Field select where the rule applies; empty or "*" matches any report or field
+// Absolute is the accepted absolute difference and Percent the accepted difference relative to the database value
+// a difference is within tolerance when it does not exceed at least one of the limits set (zero means unset)
+type Tolerance struct {
+	Report   string          `yaml:"report"`
+	Field    string          `yaml:"field"`
+	Absolute decimal.Decimal `yaml:"absolute"`
+	Percent  decimal.Decimal `yaml:"percent"`
+}
+
+// Validate validates the tolerance rule
+func (t Tolerance) Validate() error {
+	if t.Absolute.IsNegative() || t.Percent.IsNegative() {
+		return fmt.Errorf("invalid tolerance for report %q field %q: limits must not be negative", t.Report, t.Field)
+	}
+	if t.Absolute.IsZero() && t.Percent.IsZero() {
+		return fmt.Errorf("invalid tolerance for report %q field %q: absolute or percent limit is required", t.Report, t.Field)
+	}
+	return nil
+}
+
+// Matches reports whether the rule applies to a field of a report
+// names are matched case-insensitively, with or without the .TXT extension on reports
+func (t Tolerance) Matches(report string, field string) bool {
+	return matchesName(strings.TrimSuffix(strings.ToUpper(t.Report), ".TXT"), strings.ToUpper(report)) &&
+		matchesName(strings.ToUpper(t.Field), strings.ToUpper(field))
+}
+
+// Accepts reports whether a difference is inside the absolute or the percent limit of the rule
+// relative differences are not defined for a zero database value
+func (t Tolerance) Accepts(diff *FieldDifference) bool {
+	if t.Absolute.IsPositive() && diff.AbsDelta != "" {
+		delta, err := decimal.NewFromString(diff.AbsDelta)
+		if err == nil && delta.Abs().LessThanOrEqual(t.Absolute) {
+			return true
+		}
+	}
+	if t.Percent.IsPositive() && diff.RelDelta != "" {
+		delta, err := decimal.NewFromString(diff.RelDelta)
+		if err == nil && delta.Abs().LessThanOrEqual(t.Percent) {
+			return true
+		}
+	}
+	return false
+}
+
+// withinTolerance reports whether any rule accepts the difference of a report field
+func withinTolerance(tolerances []Tolerance, report string, diff *FieldDifference) bool {
+	for _, t := range tolerances {
+		if t.Matches(report, diff.Field) && t.Accepts(diff) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesName reports whether a rule name selects a name; empty or "*" selects any
+func matchesName(rule string, name string) bool {
+	rule = strings.TrimSpace(rule)
+	return rule == "" || rule == "*" || rule == name
+}
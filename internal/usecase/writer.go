@@ -0,0 +1,117 @@
+package usecase
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/lavinas/cadoc6334/internal/domain"
+)
+
+// fileMode is the permission of a new report file; a replaced file keeps its own
+const fileMode os.FileMode = 0644
+
+// fileWriter writes a report file line by line into a temporary file that only
+// replaces the target on Commit, so a failed run never leaves a half-written file
+type fileWriter struct {
+	path    string
+	file    *os.File
+	buf     *bufio.Writer
+	hash    hash.Hash
+	enc     domain.Encoding
+	profile OutputProfile
+	lines   int64
+}
+
+// newFileWriter creates a new fileWriter for the target path
+// profile sets the charset of each line, the line terminator and whether the last line is terminated
+func newFileWriter(path string, profile OutputProfile) (*fileWriter, error) {
+	enc, err := profile.GetEncoding()
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), ".cadoc-*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("error creating file: %w", err)
+	}
+	h := sha256.New()
+	return &fileWriter{
+		path:    path,
+		file:    file,
+		buf:     bufio.NewWriter(io.MultiWriter(file, h)),
+		hash:    h,
+		enc:     enc,
+		profile: profile,
+	}, nil
+}
+
+// WriteLine writes one line followed by the line terminator
+// without a final newline, the terminator is written before every line but the first instead
+func (fw *fileWriter) WriteLine(line string) error {
+	out, err := fw.enc.Encode(line)
+	if err != nil {
+		return fmt.Errorf("error encoding line %d: %w", fw.lines+1, err)
+	}
+	if !fw.profile.HasFinalNewline() && fw.lines > 0 {
+		out = append([]byte(fw.profile.Terminator()), out...)
+	}
+	if fw.profile.HasFinalNewline() {
+		out = append(out, fw.profile.Terminator()...)
+	}
+	if _, err := fw.buf.Write(out); err != nil {
+		return fmt.Errorf("error writing line %d: %w", fw.lines+1, err)
+	}
+	fw.lines++
+	return nil
+}
+
+// Commit flushes the file, moves it over the target path and returns its SHA-256 checksum
+// the file gets the mode of the target it replaces, or fileMode, as the temporary file is owner-only
+func (fw *fileWriter) Commit() (string, error) {
+	if err := fw.buf.Flush(); err != nil {
+		fw.Abort()
+		return "", fmt.Errorf("error writing file: %w", err)
+	}
+	mode := fileMode
+	if info, err := os.Stat(fw.path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := fw.file.Chmod(mode); err != nil {
+		fw.Abort()
+		return "", fmt.Errorf("error setting file mode: %w", err)
+	}
+	if err := fw.file.Close(); err != nil {
+		os.Remove(fw.file.Name())
+		return "", fmt.Errorf("error closing file: %w", err)
+	}
+	if err := os.Rename(fw.file.Name(), fw.path); err != nil {
+		os.Remove(fw.file.Name())
+		return "", fmt.Errorf("error moving file into place: %w", err)
+	}
+	return hex.EncodeToString(fw.hash.Sum(nil)), nil
+}
+
+// Abort discards the temporary file
+func (fw *fileWriter) Abort() {
+	fw.file.Close()
+	os.Remove(fw.file.Name())
+}
+
+// fileChecksum returns the SHA-256 checksum of a file
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
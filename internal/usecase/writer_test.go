@@ -0,0 +1,92 @@
+package usecase
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileWriterMode(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		existing os.FileMode
+		want     os.FileMode
+	}{
+		{name: "new file", want: fileMode},
+		{name: "replaced file", existing: 0640, want: 0640},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".txt")
+			if tt.existing != 0 {
+				if err := os.WriteFile(path, []byte("old\n"), tt.existing); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chmod(path, tt.existing); err != nil {
+					t.Fatal(err)
+				}
+			}
+			writer, err := newFileWriter(path, OutputProfile{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := writer.WriteLine("line"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := writer.Commit(); err != nil {
+				t.Fatal(err)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := info.Mode().Perm(); got != tt.want {
+				t.Errorf("file mode %v, expected %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileWriterTerminator(t *testing.T) {
+	no := false
+	tests := []struct {
+		name    string
+		profile OutputProfile
+		want    string
+	}{
+		{name: "default", profile: OutputProfile{}, want: "a\nb\nc\n"},
+		{name: "lf", profile: OutputProfile{LineEnding: LineEndingLF}, want: "a\nb\nc\n"},
+		{name: "crlf", profile: OutputProfile{LineEnding: LineEndingCRLF}, want: "a\r\nb\r\nc\r\n"},
+		{name: "lf without final newline", profile: OutputProfile{FinalNewline: &no}, want: "a\nb\nc"},
+		{name: "crlf without final newline", profile: OutputProfile{LineEnding: LineEndingCRLF, FinalNewline: &no}, want: "a\r\nb\r\nc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "file.txt")
+			writer, err := newFileWriter(path, tt.profile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, line := range []string{"a", "b", "c"} {
+				if err := writer.WriteLine(line); err != nil {
+					t.Fatal(err)
+				}
+			}
+			checksum, err := writer.Commit()
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("file holds %q, expected %q", data, tt.want)
+			}
+			if onDisk, _ := fileChecksum(path); onDisk != checksum {
+				t.Errorf("Commit() checksum %s, file checksum %s", checksum, onDisk)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+drop table if exists pix_generation;
+
+create table pix_generation (
+    datatransacao date primary key,
+    file_name varchar(50) not null,
+    records bigint not null,
+    checksum varchar(64) not null,
+    source_records bigint not null,
+    source_digest varchar(32) not null,
+    generated_at timestamp not null
+);
+
+-- state of the pix_dimp rows of each day: incremental generation compares it to the one of the file generated
+create or replace view pix_source as
+select p.datatransacao::date as datatransacao,
+       count(*) as records,
+       md5(string_agg(p::text, '|' order by p::text)) as digest
+  from pix_dimp p
+ group by p.datatransacao::date;
+
+select * from pix_generation order by datatransacao;